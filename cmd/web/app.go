@@ -2,8 +2,10 @@ package main
 
 import (
 	"html/template"
-	"log"
+	"log/slog"
 	"net/url"
+	"sync"
+	"sync/atomic"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/micahco/racket-connections/internal/mailer"
@@ -12,20 +14,45 @@ import (
 
 type application struct {
 	isDevelopment  bool
-	errorLog       *log.Logger
-	infoLog        *log.Logger
+	logger         *slog.Logger
 	baseURL        *url.URL
 	models         models.Models
 	templateCache  map[string]*template.Template
 	sessionManager *scs.SessionManager
 	mailer         *mailer.Mailer
+	oidc           *oidcProvider // nil when single sign-on is not configured
+	metrics        *metrics
+	metricsToken   string // serves /metrics on the main router when set
+	clientIPHeader string // trusted proxy header holding the client address
+	jobs           sync.WaitGroup
+	pendingJobs    atomic.Int64  // one-off jobs still running
+	workers        atomic.Int64  // long running workers still running
+	shutdown       chan struct{} // closed when the server begins shutting down
+	outbox         chan struct{} // wakes the outbox worker
 }
 
+// Runs a one-off job in a goroutine that the server waits for before exiting
 func (app *application) background(fn func()) {
+	app.track(&app.pendingJobs, fn)
+}
+
+// Runs a long running worker that the server waits for before exiting.
+// Workers must return once app.shutdown is closed.
+func (app *application) worker(fn func()) {
+	app.track(&app.workers, fn)
+}
+
+func (app *application) track(count *atomic.Int64, fn func()) {
+	app.jobs.Add(1)
+	count.Add(1)
+
 	go func() {
+		defer app.jobs.Done()
+		defer count.Add(-1)
+
 		defer func() {
 			if err := recover(); err != nil {
-				app.infoLog.Println("BACKGROUND:", err)
+				app.logger.Error("background panic", "error", err)
 			}
 		}()
 		fn()
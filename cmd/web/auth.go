@@ -20,7 +20,17 @@ const (
 	verificationTokenSessionKey   = "verificationToken"
 	resetEmailSessionKey          = "resetEmail"
 	resetTokenSessionKey          = "resetToken"
+	twoFactorUserIDSessionKey     = "twoFactorUserID"
+	twoFactorExpirySessionKey     = "twoFactorExpiry"
+	totpKeySessionKey             = "totpKey"
+	oidcStateSessionKey           = "oidcState"
+	oidcNonceSessionKey           = "oidcNonce"
+	oidcVerifierSessionKey        = "oidcVerifier"
+	oidcSubjectSessionKey         = "oidcSubject"
+	oidcEmailSessionKey           = "oidcEmail"
+	oidcNameSessionKey            = "oidcName"
 	isAuthenticatedContextKey     = contextKey("isAuthenticated")
+	requestIDContextKey           = contextKey("requestID")
 )
 
 func (app *application) login(r *http.Request, userID int) error {
@@ -31,11 +41,40 @@ func (app *application) login(r *http.Request, userID int) error {
 
 	app.sessionManager.Put(r.Context(), string(authenticatedUserIDSessionKey), userID)
 
-	return nil
+	token := app.sessionManager.Token(r.Context())
+
+	err = app.models.Session.Insert(r.Context(), token, userID, app.clientIP(r), r.UserAgent())
+	if err != nil {
+		return err
+	}
+
+	// Logging in during the grace period cancels a requested deletion
+	cancelled, err := app.models.Deletion.Cancel(r.Context(), userID)
+	if err != nil || !cancelled {
+		return err
+	}
+
+	u, err := app.models.User.GetProfile(r.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	f := FlashMessage{
+		Type:    FlashInfo,
+		Message: "Welcome back! Your account is no longer scheduled for deletion.",
+	}
+	app.flash(r, f)
+
+	return app.enqueueEmail(r.Context(), u.Email, "account_deletion_cancelled.tmpl", deletionEmailData{Name: u.Name})
 }
 
 func (app *application) logout(r *http.Request) error {
-	err := app.sessionManager.RenewToken(r.Context())
+	err := app.models.Session.Delete(r.Context(), app.sessionManager.Token(r.Context()))
+	if err != nil {
+		return err
+	}
+
+	err = app.sessionManager.RenewToken(r.Context())
 	if err != nil {
 		return err
 	}
@@ -98,10 +137,22 @@ func (app *application) handleAuthLoginPost(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	id, err := app.models.User.Authenticate(form.email, form.password)
-	if err != nil {
+	if !app.allowAttempt(w, r, "login", form.email) {
+		return
+	}
+
+	lockout, ok := app.checkLockout(w, r, form.email)
+	if !ok {
+		return
+	}
+
+	id, err := app.models.User.Authenticate(r.Context(), form.email, form.password)
+	if errors.Is(err, models.ErrRehash) {
+		// The password matched, so keep the old hash and log in anyway
+		app.logger.WarnContext(r.Context(), "password rehash failed", "user", id, "error", err)
+	} else if err != nil {
 		if errors.Is(err, models.ErrInvalidCredentials) {
-			unauthorizedError(w)
+			app.loginFailed(w, r, form.email, lockout)
 		} else {
 			app.serverError(w, r, err)
 		}
@@ -109,6 +160,34 @@ func (app *application) handleAuthLoginPost(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	err = app.models.AuthAttempt.Insert(r.Context(), "login", app.clientIP(r), form.email, false)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	// Wait for a second factor before logging in
+	hasTOTP, err := app.models.TOTP.Exists(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	if hasTOTP {
+		err = app.beginTwoFactor(r, id)
+		if err != nil {
+			app.serverError(w, r, err)
+
+			return
+		}
+
+		http.Redirect(w, r, "/auth/2fa", http.StatusSeeOther)
+
+		return
+	}
+
 	err = app.login(r, id)
 	if err != nil {
 		app.serverError(w, r, err)
@@ -161,6 +240,10 @@ func (app *application) handleAuthSignupPost(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if !app.allowAttempt(w, r, "signup", form.email) {
+		return
+	}
+
 	// Consistent flash message
 	f := FlashMessage{
 		Type:    FlashInfo,
@@ -168,7 +251,7 @@ func (app *application) handleAuthSignupPost(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Check if user with email already exists
-	exists, err := app.models.User.ExistsEmail(form.email)
+	exists, err := app.models.User.ExistsEmail(r.Context(), form.email)
 	if err != nil {
 		app.serverError(w, r, err)
 
@@ -185,7 +268,7 @@ func (app *application) handleAuthSignupPost(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Check if link verification has already been created
-	v, err := app.models.Verification.Get(form.email)
+	v, err := app.models.Verification.Latest(r.Context(), models.PurposeSignup, form.email)
 	if err != nil && err != models.ErrNoRecord {
 		app.serverError(w, r, err)
 
@@ -226,19 +309,14 @@ func (app *application) handleAuthSignupPost(w http.ResponseWriter, r *http.Requ
 
 	link := app.baseURL.ResolveReference(ref)
 
-	// Disable mailer during development during alpha stage
-	if app.isDevelopment {
-		fmt.Println("Verification link:", link)
-	} else {
-		app.background(func() {
-			err = app.mailer.Send(form.email, "email_verification.tmpl", link)
-			if err != nil {
-				app.errorLog.Println(err)
-			}
-		})
+	err = app.enqueueEmail(r.Context(), form.email, "email_verification.tmpl", link)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
 	}
 
-	err = app.models.Verification.Insert(token, form.email)
+	err = app.models.Verification.Insert(r.Context(), token, models.PurposeSignup, form.email)
 	if err != nil {
 		app.serverError(w, r, err)
 
@@ -277,9 +355,9 @@ func (app *application) handleAuthRegisterGet(w http.ResponseWriter, r *http.Req
 
 	app.sessionManager.Put(r.Context(), verificationTokenSessionKey, queryToken)
 
-	m, _ := app.models.Contact.Methods()
-	d, _ := app.models.Timeslot.Days()
-	t, _ := app.models.Timeslot.Times()
+	m, _ := app.models.Contact.Methods(r.Context())
+	d, _ := app.models.Timeslot.Days(r.Context())
+	t, _ := app.models.Timeslot.Times(r.Context())
 
 	exists := app.sessionManager.Exists(r.Context(), verificationEmailSessionKey)
 
@@ -343,6 +421,12 @@ func (app *application) handleAuthRegisterPost(w http.ResponseWriter, r *http.Re
 	form.Validate(validator.NotBlank(form.password), "invalid password: cannot be blank")
 	form.Validate(validator.MinChars(form.password, 8), "invalid password: must be at least 8 characters long")
 	form.Validate(validator.MaxChars(form.password, 72), "invalid password: must be no more than 72 characters long")
+
+	if validator.MinChars(form.password, 8) {
+		problem := validator.PasswordProblem(form.password, email, form.name)
+		form.Validate(problem == "", "invalid password: "+problem)
+	}
+
 	form.Validate(validator.NotBlank(form.contactValue), "invalid contact value: cannot be blank")
 
 	switch form.contactMethod {
@@ -373,33 +457,39 @@ func (app *application) handleAuthRegisterPost(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err = app.models.Verification.Verify(token, email)
-	if err != nil {
-		if errors.Is(err, models.ErrNoRecord) {
-			unauthorizedError(w)
-		} else if errors.Is(err, models.ErrExpiredVerification) {
-			app.flash(r, ExpiredTokenFlash)
-
-			http.Redirect(w, r, "/", http.StatusSeeOther)
-		} else {
-			app.serverError(w, r, err)
+	// Consume the token and insert the user's data together, so a failure
+	// leaves neither a token used up nor a user without contacts
+	var userID int
+	err = app.models.Tx.Do(r.Context(), func(tx models.Models) error {
+		err := tx.Verification.Consume(r.Context(), token, models.PurposeSignup, email)
+		if err != nil {
+			return err
 		}
 
-		return
-	}
+		userID, err = tx.User.Insert(r.Context(), form.name, email, form.password)
+		if err != nil {
+			return err
+		}
 
-	err = app.models.Verification.Purge(email)
-	if err != nil {
-		app.serverError(w, r, err)
+		methodID, err := tx.Contact.MethodID(r.Context(), form.contactMethod)
+		if err != nil {
+			return err
+		}
 
-		return
-	}
+		err = tx.Contact.Insert(r.Context(), form.contactValue, userID, methodID)
+		if err != nil {
+			return err
+		}
 
-	// Insert data
-	userID, err := app.models.User.Insert(form.name, email, form.password)
+		return insertTimeslots(r, tx, userID)
+	})
 	if err != nil {
-		if errors.Is(err, models.ErrDuplicateEmail) {
+		if errors.Is(err, models.ErrNoRecord) || errors.Is(err, models.ErrDuplicateEmail) {
 			unauthorizedError(w)
+		} else if errors.Is(err, models.ErrExpiredVerification) {
+			app.flash(r, ExpiredTokenFlash)
+
+			http.Redirect(w, r, "/", http.StatusSeeOther)
 		} else {
 			app.serverError(w, r, err)
 		}
@@ -407,31 +497,6 @@ func (app *application) handleAuthRegisterPost(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	methodID, err := app.models.Contact.MethodID(form.contactMethod)
-	if err != nil {
-		app.serverError(w, r, err)
-	}
-
-	err = app.models.Contact.Insert(form.contactValue, userID, methodID)
-	if err != nil {
-		app.serverError(w, r, err)
-	}
-
-	// Parse timetable and insert data
-	days, _ := app.models.Timeslot.Days()
-	times, _ := app.models.Timeslot.Times()
-	for _, d := range days {
-		for _, t := range times {
-			key := fmt.Sprintf("%s-%s", d.Abbrev, t.Abbrev)
-			if r.Form.Get(key) == "on" {
-				err = app.models.Timeslot.Insert(userID, d.ID, t.ID)
-				if err != nil {
-					app.serverError(w, r, err)
-				}
-			}
-		}
-	}
-
 	// Login user
 	app.sessionManager.Clear(r.Context())
 	err = app.login(r, userID)
@@ -478,7 +543,11 @@ func (app *application) handleAuthResetPost(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	exists, err := app.models.User.ExistsEmail(form.email)
+	if !app.allowAttempt(w, r, "reset", form.email) {
+		return
+	}
+
+	exists, err := app.models.User.ExistsEmail(r.Context(), form.email)
 	if err != nil {
 		app.serverError(w, r, err)
 
@@ -520,19 +589,14 @@ func (app *application) handleAuthResetPost(w http.ResponseWriter, r *http.Reque
 
 	link := app.baseURL.ResolveReference(ref)
 
-	// Disable mailer during development during alpha stage
-	if app.isDevelopment {
-		fmt.Println("Reset link:", link.String())
-	} else {
-		app.background(func() {
-			err = app.mailer.Send(form.email, "reset_password.tmpl", link.String())
-			if err != nil {
-				app.errorLog.Println(err)
-			}
-		})
+	err = app.enqueueEmail(r.Context(), form.email, "reset_password.tmpl", link.String())
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
 	}
 
-	err = app.models.Verification.Insert(token, form.email)
+	err = app.models.Verification.Insert(r.Context(), token, models.PurposeReset, form.email)
 	if err != nil {
 		app.serverError(w, r, err)
 
@@ -602,6 +666,11 @@ func (app *application) handleAuthResetUpdatePost(w http.ResponseWriter, r *http
 	form.Validate(validator.MinChars(form.password, 8), "invalid password: must be at least 8 characters long")
 	form.Validate(validator.MaxChars(form.password, 72), "invalid password: must be no more than 72 characters long")
 
+	if validator.MinChars(form.password, 8) {
+		problem := validator.PasswordProblem(form.password, email)
+		form.Validate(problem == "", "invalid password: "+problem)
+	}
+
 	if !form.IsValid() {
 		validationError(w, form.Validator)
 
@@ -615,7 +684,7 @@ func (app *application) handleAuthResetUpdatePost(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = app.models.Verification.Verify(token, email)
+	err = app.models.Verification.Consume(r.Context(), token, models.PurposeReset, email)
 	if err != nil {
 		if errors.Is(err, models.ErrNoRecord) {
 			unauthorizedError(w)
@@ -630,14 +699,29 @@ func (app *application) handleAuthResetUpdatePost(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = app.models.Verification.Purge(email)
+	err = app.models.User.UpdatePassword(r.Context(), email, form.password)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	err = app.models.Lockout.Delete(r.Context(), email)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	// Sign out every device in case the old password was compromised
+	userID, err := app.models.User.GetIDByEmail(r.Context(), email)
 	if err != nil {
 		app.serverError(w, r, err)
 
 		return
 	}
 
-	err = app.models.User.UpdatePassword(email, form.password)
+	err = app.models.Session.DeleteOthers(r.Context(), userID, "")
 	if err != nil {
 		app.serverError(w, r, err)
 
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/micahco/racket-connections/internal/assert"
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+func TestAuthLoginPost(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	_, err := app.models.User.Insert(context.Background(), testName, testEmail, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		email        string
+		password     string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Valid",
+			email:        testEmail,
+			password:     testPassword,
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/",
+		},
+		{
+			name:         "Email case",
+			email:        strings.ToUpper(testEmail),
+			password:     testPassword,
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/",
+		},
+		{
+			name:     "Wrong password",
+			email:    testEmail,
+			password: "Quiet-Lantern-Ferry-17",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Unknown email",
+			email:    "timmy@oregonstate.edu",
+			password: testPassword,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Blank email",
+			email:    "",
+			password: testPassword,
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Blank password",
+			email:    testEmail,
+			password: "",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, app.routes())
+
+			form := url.Values{}
+			form.Add("email", tt.email)
+			form.Add("password", tt.password)
+
+			code, header, _ := ts.postForm(t, "/auth/login", "/", form)
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Location"), tt.wantLocation)
+		})
+	}
+}
+
+func TestAuthSignupPost(t *testing.T) {
+	app, mt := newTestApplication(t)
+
+	_, err := app.models.User.Insert(context.Background(), testName, testEmail, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		email     string
+		wantCode  int
+		wantEmail bool
+	}{
+		{
+			name:      "Valid",
+			email:     "timmy@oregonstate.edu",
+			wantCode:  http.StatusSeeOther,
+			wantEmail: true,
+		},
+		{
+			name:      "Existing user",
+			email:     testEmail,
+			wantCode:  http.StatusSeeOther,
+			wantEmail: false,
+		},
+		{
+			name:      "Other domain",
+			email:     "timmy@example.com",
+			wantCode:  http.StatusUnprocessableEntity,
+			wantEmail: false,
+		},
+		{
+			name:      "Blank email",
+			email:     "",
+			wantCode:  http.StatusUnprocessableEntity,
+			wantEmail: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, app.routes())
+
+			form := url.Values{}
+			form.Add("email", tt.email)
+
+			code, _, _ := ts.postForm(t, "/auth/signup", "/", form)
+			assert.Equal(t, code, tt.wantCode)
+
+			app.sendDueEmails()
+			assert.Equal(t, mt.Last(tt.email) != nil, tt.wantEmail)
+		})
+	}
+}
+
+func TestAuthRegisterPost(t *testing.T) {
+	app, mt := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	form := url.Values{}
+	form.Add("email", testEmail)
+
+	code, _, _ := ts.postForm(t, "/auth/signup", "/", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	link := emailedLink(t, app, mt, testEmail)
+
+	register := func(password, contactMethod string) (int, http.Header) {
+		form := url.Values{}
+		form.Add("name", testName)
+		form.Add("email", testEmail)
+		form.Add("password", password)
+		form.Add("contact-method", contactMethod)
+		form.Add("contact-value", "541-737-1000")
+
+		code, header, _ := ts.postForm(t, "/auth/register", link, form)
+
+		return code, header
+	}
+
+	code, _ = register("password", "phone")
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+
+	code, _ = register(testPassword, "carrier pigeon")
+	assert.Equal(t, code, http.StatusBadRequest)
+
+	code, header := register(testPassword, "phone")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/")
+
+	id, err := app.models.User.GetIDByEmail(context.Background(), testEmail)
+	assert.Equal(t, err, nil)
+
+	contacts, err := app.models.Contact.UserContacts(context.Background(), id)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(contacts), 1)
+	assert.Equal(t, contacts[0].Method, "phone")
+
+	// The token cannot be used twice
+	ts = newTestServer(t, app.routes())
+
+	code, _ = register("Amber-Compass-Meadow-31", "phone")
+	assert.Equal(t, code, http.StatusUnauthorized)
+}
+
+// A failed registration rolls back, leaving the token unused
+func TestAuthRegisterPostExistingUser(t *testing.T) {
+	app, mt := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	form := url.Values{}
+	form.Add("email", testEmail)
+
+	code, _, _ := ts.postForm(t, "/auth/signup", "/", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	link := emailedLink(t, app, mt, testEmail)
+
+	_, err := app.models.User.Insert(context.Background(), testName, testEmail, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form = url.Values{}
+	form.Add("name", testName)
+	form.Add("email", testEmail)
+	form.Add("password", testPassword)
+	form.Add("contact-method", "phone")
+	form.Add("contact-value", "541-737-1000")
+
+	code, _, _ = ts.postForm(t, "/auth/register", link, form)
+	assert.Equal(t, code, http.StatusUnauthorized)
+
+	v, err := app.models.Verification.Latest(context.Background(), models.PurposeSignup, testEmail)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, v.ConsumedAt == nil, true)
+}
+
+func TestAuthResetUpdatePost(t *testing.T) {
+	app, mt := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	_, err := app.models.User.Insert(context.Background(), testName, testEmail, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{}
+	form.Add("email", testEmail)
+
+	code, _, _ := ts.postForm(t, "/auth/reset", "/auth/reset", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	link := emailedLink(t, app, mt, testEmail)
+	assert.Equal(t, strings.HasPrefix(link, "/auth/reset/update?token="), true)
+
+	newPassword := "Amber-Compass-Meadow-31"
+
+	form = url.Values{}
+	form.Add("password", newPassword)
+
+	code, header, _ := ts.postForm(t, "/auth/reset/update", link, form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/auth/login")
+
+	_, err = app.models.User.Authenticate(context.Background(), testEmail, testPassword)
+	assert.Equal(t, err != nil, true)
+
+	ts.login(t, testEmail, newPassword)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/micahco/racket-connections/internal/mailer"
+)
+
+// Sample data for previewing each email template
+func (app *application) emailSamples() map[string]interface{} {
+	link := func(p string) string {
+		ref := &url.URL{Path: p, RawQuery: "token=sample"}
+		return app.baseURL.ResolveReference(ref).String()
+	}
+
+	deletion := deletionEmailData{
+		Name:    "Benny Beaver",
+		PurgeAt: time.Now().Add(deletionGracePeriod).Format(purgeDateLayout),
+		Link:    app.baseURL.ResolveReference(&url.URL{Path: "/auth/login"}).String(),
+	}
+
+	return map[string]interface{}{
+		"account_deleted.tmpl":            deletion,
+		"account_deletion_cancelled.tmpl": deletion,
+		"account_deletion_requested.tmpl": deletion,
+		"account_locked.tmpl": lockoutEmailData{
+			Until: time.Now().Add(lockoutDuration).Format(time.Kitchen),
+			Link:  link("/auth/reset"),
+		},
+		"data_export.tmpl": exportEmailData{
+			Name:   "Benny Beaver",
+			Expiry: time.Now().Add(exportInterval).Format(time.RFC1123),
+			Link:   link("/profile/export/download"),
+		},
+		"email_verification.tmpl": link("/auth/register"),
+		"login_link.tmpl":         link("/auth/link"),
+		"reset_password.tmpl":     link("/auth/reset/update"),
+		"tournament_registration.tmpl": tournamentRegistrationEmail{
+			Name:       "Benny Beaver",
+			Tournament: "Fall Singles Open",
+			Link:       link(tournamentURL(1)),
+		},
+	}
+}
+
+type devEmail struct {
+	Name    string
+	Subject string
+	HasHTML bool
+}
+
+type devEmailsData struct {
+	Emails []*devEmail
+}
+
+func (app *application) handleDevEmailsGet(w http.ResponseWriter, r *http.Request) {
+	files, err := mailer.Templates()
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	samples := app.emailSamples()
+
+	var data devEmailsData
+	for _, f := range files {
+		name := path.Base(f)
+
+		msg, err := mailer.Render("preview@oregonstate.edu", name, samples[name])
+		if err != nil {
+			app.serverError(w, r, err)
+
+			return
+		}
+
+		data.Emails = append(data.Emails, &devEmail{
+			Name:    strings.TrimSuffix(name, ".tmpl"),
+			Subject: msg.Subject,
+			HasHTML: msg.HTMLBody != "",
+		})
+	}
+
+	app.render(w, r, http.StatusOK, "dev-emails.html", data)
+}
+
+// Writes the rendered email as it would appear in a mail client. Append
+// ?format=text for the plain text body.
+func (app *application) handleDevEmailsNameGet(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name") + ".tmpl"
+
+	sample, ok := app.emailSamples()[name]
+	if !ok {
+		app.renderError(w, r, http.StatusNotFound, "")
+
+		return
+	}
+
+	msg, err := mailer.Render("preview@oregonstate.edu", name, sample)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	if msg.HTMLBody == "" || r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("Subject: " + msg.Subject + "\n" + msg.Body))
+
+		return
+	}
+
+	// Email layouts rely on inline styles
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; style-src 'unsafe-inline'")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(msg.HTMLBody))
+}
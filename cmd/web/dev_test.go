@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/url"
+	"path"
+	"testing"
+
+	"github.com/micahco/racket-connections/internal/mailer"
+)
+
+// Every email template must have sample data for the preview to render
+func TestEmailSamples(t *testing.T) {
+	app := &application{
+		baseURL: &url.URL{Scheme: "http", Host: "localhost:4000"},
+	}
+
+	samples := app.emailSamples()
+
+	files, err := mailer.Templates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range files {
+		name := path.Base(f)
+
+		t.Run(name, func(t *testing.T) {
+			sample, ok := samples[name]
+			if !ok {
+				t.Fatal("missing sample data")
+			}
+
+			_, err := mailer.Render("preview@oregonstate.edu", name, sample)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 	"runtime/debug"
+	"strconv"
+	"time"
 
+	"github.com/micahco/racket-connections/internal/models"
 	"github.com/micahco/racket-connections/internal/validator"
 )
 
@@ -12,6 +15,7 @@ type errorPageData struct {
 	Code       int
 	StatusText string
 	Message    string
+	RequestID  string
 }
 
 func (app *application) renderError(w http.ResponseWriter, r *http.Request, status int, message string) {
@@ -19,18 +23,48 @@ func (app *application) renderError(w http.ResponseWriter, r *http.Request, stat
 		Code:       status,
 		StatusText: http.StatusText(status),
 		Message:    message,
+		RequestID:  requestID(r.Context()),
 	}
 
 	app.render(w, r, status, "error.html", data)
 }
 
+// How long clients should wait before retrying after a query timeout
+const timeoutRetryAfter = 5 * time.Second
+
 func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
-	trace := fmt.Sprintf("%s\n%s", err.Error(), debug.Stack())
-	app.errorLog.Output(2, trace)
+	if errors.Is(err, models.ErrTimeout) {
+		app.serviceUnavailable(w, r, err)
+
+		return
+	}
+
+	app.logger.ErrorContext(r.Context(), err.Error(),
+		"method", r.Method,
+		"uri", r.URL.RequestURI(),
+		"trace", string(debug.Stack()))
 
 	app.renderError(w, r, http.StatusInternalServerError, "")
 }
 
+// Query timeouts are usually caused by load rather than a bug, so they are
+// logged without a stack trace and the client is asked to retry
+func (app *application) serviceUnavailable(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.WarnContext(r.Context(), err.Error(),
+		"method", r.Method,
+		"uri", r.URL.RequestURI())
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(timeoutRetryAfter.Seconds())))
+
+	app.renderError(w, r, http.StatusServiceUnavailable, "The server is busy. Please try again in a moment.")
+}
+
+func (app *application) tooManyRequests(w http.ResponseWriter, r *http.Request, retryAfter time.Duration, message string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+
+	app.renderError(w, r, http.StatusTooManyRequests, message)
+}
+
 func unauthorizedError(w http.ResponseWriter) {
 	http.Error(w, "permission denied", http.StatusUnauthorized)
 }
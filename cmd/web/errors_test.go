@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/micahco/racket-connections/internal/assert"
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+func TestServerError(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	tests := []struct {
+		name           string
+		err            error
+		wantCode       int
+		wantRetryAfter string
+	}{
+		{
+			name:     "Error",
+			err:      errors.New("boom"),
+			wantCode: http.StatusInternalServerError,
+		},
+		{
+			name:           "Query timeout",
+			err:            fmt.Errorf("%w: deadline exceeded", models.ErrTimeout),
+			wantCode:       http.StatusServiceUnavailable,
+			wantRetryAfter: "5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				app.serverError(w, r, tt.err)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			app.sessionManager.LoadAndSave(next).ServeHTTP(rr, r)
+
+			assert.Equal(t, rr.Code, tt.wantCode)
+			assert.Equal(t, rr.Header().Get("Retry-After"), tt.wantRetryAfter)
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/crypto"
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+// Users may request one export per day. The emailed link is a verification
+// token, so it expires with the same window.
+const exportInterval = 24 * time.Hour
+
+type exportEmailData struct {
+	Name   string
+	Expiry string
+	Link   string
+}
+
+var ExpiredExportFlash = FlashMessage{
+	Type:    FlashError,
+	Message: "That download link has expired. Please request a new export.",
+}
+
+// Emails the user a link to download a copy of their data
+func (app *application) handleProfileExportPost(w http.ResponseWriter, r *http.Request) {
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	u, err := app.models.User.GetProfile(r.Context(), suid)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	v, err := app.models.Verification.Latest(r.Context(), models.PurposeExport, u.Email)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	if v != nil {
+		next := v.CreatedAt.Add(exportInterval)
+		if time.Now().Before(next) {
+			app.tooManyRequests(w, r, time.Until(next), "You can only export your data once per day. Check your email for the last download link.")
+
+			return
+		}
+	}
+
+	token, err := crypto.GenerateRandomString(32)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	// Create link reference to download endpoint
+	ref, err := url.Parse("/profile/export/download")
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	// Set token query
+	q := ref.Query()
+	q.Set("token", token)
+	ref.RawQuery = q.Encode()
+
+	data := exportEmailData{
+		Name:   u.Name,
+		Expiry: time.Now().Add(exportInterval).Format(time.RFC1123),
+		Link:   app.baseURL.ResolveReference(ref).String(),
+	}
+
+	// The token also rate limits exports, so it is only stored if the email
+	// carrying it is queued too
+	err = app.models.Tx.Do(r.Context(), func(tx models.Models) error {
+		err := tx.Verification.Insert(r.Context(), token, models.PurposeExport, u.Email)
+		if err != nil {
+			return err
+		}
+
+		return insertEmail(r.Context(), tx, u.Email, "data_export.tmpl", data)
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	app.wakeOutbox()
+
+	f := FlashMessage{
+		Type:    FlashSuccess,
+		Message: "We've emailed you a link to download your data. It expires in 24 hours.",
+	}
+	app.flash(r, f)
+
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+// Serves the export as a ZIP. The link only works for the account that
+// requested it, while logged in, until it expires.
+func (app *application) handleProfileExportDownloadGet(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		unauthorizedError(w)
+
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	u, err := app.models.User.GetProfile(r.Context(), suid)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	// Only the latest token is valid since a new one cannot be issued
+	// until the last has expired
+	v, err := app.models.Verification.Latest(r.Context(), models.PurposeExport, u.Email)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			unauthorizedError(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(v.TokenHash), []byte(crypto.HashToken(token))) != 1 {
+		unauthorizedError(w)
+
+		return
+	}
+
+	if v.IsExpired() {
+		app.flash(r, ExpiredExportFlash)
+
+		http.Redirect(w, r, "/profile", http.StatusSeeOther)
+
+		return
+	}
+
+	tables, err := app.exportTables(r, suid, u)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	// Build the archive in memory so a failure can still be reported
+	buf := new(bytes.Buffer)
+
+	err = writeExport(buf, tables)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	filename := fmt.Sprintf("racket-connections-%s.zip", time.Now().Format("2006-01-02"))
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+
+	_, err = buf.WriteTo(w)
+	if err != nil {
+		app.logger.Error("export write failed", "user", suid, "error", err)
+	}
+}
+
+// A section of the export, written as both a CSV file and a JSON array
+type exportTable struct {
+	name   string
+	header []string
+	rows   [][]string
+}
+
+func formatExportTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (app *application) exportTables(r *http.Request, userID int, u *models.UserProfile) ([]*exportTable, error) {
+	profile := &exportTable{
+		name:   "profile",
+		header: []string{"name", "email"},
+		rows:   [][]string{{u.Name, u.Email}},
+	}
+
+	contacts, err := app.models.Contact.UserContacts(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	contactsTable := &exportTable{
+		name:   "contacts",
+		header: []string{"method", "value"},
+	}
+	for _, c := range contacts {
+		contactsTable.rows = append(contactsTable.rows, []string{c.Method, c.Value})
+	}
+
+	timeslots, err := app.models.Timeslot.User(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	timeslotsTable := &exportTable{
+		name:   "availability",
+		header: []string{"day", "time"},
+	}
+	for _, t := range timeslots {
+		timeslotsTable.rows = append(timeslotsTable.rows, []string{t.Day.Name, t.Time.Name})
+	}
+
+	posts, err := app.models.Post.User(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	postsTable := &exportTable{
+		name:   "posts",
+		header: []string{"id", "sport", "skill_level", "format", "players_needed", "comment", "created_at"},
+	}
+	for _, p := range posts {
+		postsTable.rows = append(postsTable.rows, []string{
+			strconv.Itoa(p.ID),
+			p.Sport,
+			p.SkillLevel,
+			p.Format,
+			strconv.Itoa(p.PlayersNeeded),
+			p.Comment,
+			formatExportTime(p.CreatedAt),
+		})
+	}
+
+	rosters, err := app.models.Roster.User(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rostersTable := &exportTable{
+		name:   "rosters",
+		header: []string{"post_id", "sport", "posted_by", "joined_at"},
+	}
+	for _, rp := range rosters {
+		rostersTable.rows = append(rostersTable.rows, []string{
+			strconv.Itoa(rp.PostID),
+			rp.Sport,
+			rp.PostedBy,
+			formatExportTime(rp.JoinedAt),
+		})
+	}
+
+	identities, err := app.models.Identity.User(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	identitiesTable := &exportTable{
+		name:   "identities",
+		header: []string{"issuer", "subject", "created_at"},
+	}
+	for _, i := range identities {
+		identitiesTable.rows = append(identitiesTable.rows, []string{
+			i.Issuer,
+			i.Subject,
+			formatExportTime(i.CreatedAt),
+		})
+	}
+
+	sessions, err := app.models.Session.User(r.Context(), userID, app.sessionManager.Token(r.Context()))
+	if err != nil {
+		return nil, err
+	}
+
+	sessionsTable := &exportTable{
+		name:   "sessions",
+		header: []string{"ip", "user_agent", "current", "created_at", "last_seen_at"},
+	}
+	for _, s := range sessions {
+		sessionsTable.rows = append(sessionsTable.rows, []string{
+			s.IP,
+			s.UserAgent,
+			strconv.FormatBool(s.IsCurrent),
+			formatExportTime(s.CreatedAt),
+			formatExportTime(s.LastSeenAt),
+		})
+	}
+
+	return []*exportTable{
+		profile,
+		contactsTable,
+		timeslotsTable,
+		postsTable,
+		rostersTable,
+		identitiesTable,
+		sessionsTable,
+	}, nil
+}
+
+// Prefixes cells that a spreadsheet would otherwise evaluate as a formula.
+// Only the CSV files are escaped; data.json keeps the values as stored.
+func escapeCSVCell(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+
+	return cell
+}
+
+// Writes a ZIP with one CSV file per table and a single data.json holding
+// every table keyed by name
+func writeExport(buf *bytes.Buffer, tables []*exportTable) error {
+	zw := zip.NewWriter(buf)
+
+	data := make(map[string][]map[string]string, len(tables))
+
+	for _, t := range tables {
+		f, err := zw.Create(t.name + ".csv")
+		if err != nil {
+			return err
+		}
+
+		cw := csv.NewWriter(f)
+
+		err = cw.Write(t.header)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range t.rows {
+			escaped := make([]string, len(row))
+			for i, cell := range row {
+				escaped[i] = escapeCSVCell(cell)
+			}
+
+			err = cw.Write(escaped)
+			if err != nil {
+				return err
+			}
+		}
+
+		cw.Flush()
+
+		err = cw.Error()
+		if err != nil {
+			return err
+		}
+
+		records := make([]map[string]string, 0, len(t.rows))
+		for _, row := range t.rows {
+			record := make(map[string]string, len(t.header))
+			for i, h := range t.header {
+				record[h] = row[i]
+			}
+			records = append(records, record)
+		}
+		data[t.name] = records
+	}
+
+	f, err := zw.Create("data.json")
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+
+	err = enc.Encode(data)
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/micahco/racket-connections/internal/assert"
+)
+
+func TestSignupToPostFlow(t *testing.T) {
+	app, mt := newTestDBApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	// Signup sends a verification link
+	form := url.Values{}
+	form.Add("email", testEmail)
+
+	code, header, _ := ts.postForm(t, "/auth/signup", "/", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), ts.URL+"/")
+
+	link := emailedLink(t, app, mt, testEmail)
+	assert.Equal(t, strings.HasPrefix(link, "/auth/register?token="), true)
+
+	// Register with the emailed link logs the user in
+	form = url.Values{}
+	form.Add("name", testName)
+	form.Add("password", testPassword)
+	form.Add("contact-method", "email")
+	form.Add("contact-value", "benny@example.com")
+	form.Add("mon-mor", "on")
+
+	code, header, _ = ts.postForm(t, "/auth/register", link, form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/")
+
+	code, _, _ = ts.get(t, "/posts")
+	assert.Equal(t, code, http.StatusOK)
+
+	// Logout, then login with the new account
+	code, _, _ = ts.postForm(t, "/auth/logout", "/posts", nil)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	code, header, _ = ts.get(t, "/posts")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/auth/login")
+
+	ts.login(t, testEmail, testPassword)
+
+	// Create a tennis post
+	form = url.Values{}
+	form.Add("sport", "1")
+	form.Add("skill-level", "3")
+	form.Add("format", "1")
+	form.Add("comment", "Evenings work best")
+
+	code, header, _ = ts.postForm(t, "/posts/new", "/posts/new", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	var postID int
+	_, err := fmt.Sscanf(header.Get("Location"), "/posts/%d", &postID)
+	assert.Equal(t, err, nil)
+
+	postLink := fmt.Sprintf(`href="/posts/%d/`, postID)
+
+	// Filter posts by sport
+	tests := []struct {
+		name  string
+		query string
+		want  bool
+	}{
+		{
+			name:  "No filter",
+			query: "",
+			want:  true,
+		},
+		{
+			name:  "Matching sport",
+			query: "?sport=tennis",
+			want:  true,
+		},
+		{
+			name:  "Other sport",
+			query: "?sport=squash",
+			want:  false,
+		},
+		{
+			name:  "Matching availability",
+			query: "?sport=tennis&mon-mor=on",
+			want:  true,
+		},
+		{
+			name:  "Other availability",
+			query: "?sport=tennis&sun-eve=on",
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, "/posts"+tt.query)
+			assert.Equal(t, code, http.StatusOK)
+			assert.Equal(t, strings.Contains(body, postLink), tt.want)
+		})
+	}
+
+	// Delete the post
+	deletePath := fmt.Sprintf("/posts/%d/delete", postID)
+
+	code, header, _ = ts.postForm(t, deletePath, deletePath, nil)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/")
+
+	code, _, body := ts.get(t, "/posts")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, strings.Contains(body, postLink), false)
+
+	code, _, _ = ts.get(t, fmt.Sprintf("/posts/%d/x", postID))
+	assert.Equal(t, code, http.StatusNotFound)
+}
+
+func TestCSRF(t *testing.T) {
+	app, _ := newTestDBApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	ts.newUser(t, app, testName, testEmail, testPassword)
+
+	form := url.Values{}
+	form.Add("sport", "1")
+	form.Add("skill-level", "3")
+	form.Add("format", "1")
+
+	rs, err := ts.Client().PostForm(ts.URL+"/posts/new", form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.Body.Close()
+
+	assert.Equal(t, rs.StatusCode, http.StatusBadRequest)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+const (
+	purgeInterval     = time.Hour
+	deletionBatchSize = 100
+)
+
+// Periodically deletes expired verification tokens until shutdown
+func (app *application) purgeVerifications() {
+	app.worker(func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+
+		for {
+			n, err := app.models.Verification.Purge(context.Background())
+			if err != nil {
+				app.logger.Error(err.Error())
+			} else if n > 0 {
+				app.logger.Info("purged expired verification tokens", "count", n)
+			}
+
+			select {
+			case <-app.shutdown:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Periodically deletes old sent and dead-lettered emails until shutdown
+func (app *application) purgeEmails() {
+	app.worker(func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+
+		for {
+			n, err := app.models.Email.Purge(context.Background(), time.Now().Add(-emailRetention))
+			if err != nil {
+				app.logger.Error(err.Error())
+			} else if n > 0 {
+				app.logger.Info("purged old emails", "count", n)
+			}
+
+			select {
+			case <-app.shutdown:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Periodically deletes accounts whose grace period has passed until shutdown
+func (app *application) purgeDeletedAccounts() {
+	app.worker(func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+
+		for {
+			app.purgeDueAccounts()
+
+			select {
+			case <-app.shutdown:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+func (app *application) purgeDueAccounts() {
+	ctx := context.Background()
+
+	due, err := app.models.Deletion.Due(ctx, deletionBatchSize)
+	if err != nil {
+		app.logger.Error(err.Error())
+
+		return
+	}
+
+	for _, d := range due {
+		err = app.models.Deletion.Purge(ctx, d.UserID)
+		if err != nil {
+			// The user logged in since the deletion was fetched
+			if !errors.Is(err, models.ErrNoRecord) {
+				app.logger.Error(err.Error())
+			}
+
+			continue
+		}
+
+		app.logger.Info("purged deleted account", "user_id", d.UserID)
+
+		err = app.enqueueEmail(ctx, d.Email, "account_deleted.tmpl", deletionEmailData{Name: d.Name})
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+	}
+}
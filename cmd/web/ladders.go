@@ -0,0 +1,417 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/micahco/racket-connections/internal/models"
+	"github.com/micahco/racket-connections/internal/validator"
+)
+
+type laddersData struct {
+	Sports []*models.Sport
+}
+
+func (app *application) handleLaddersGet(w http.ResponseWriter, r *http.Request) {
+	sports, err := app.models.Sport.All(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	data := laddersData{
+		Sports: sports,
+	}
+
+	app.render(w, r, http.StatusOK, "ladders.html", data)
+}
+
+// Returns the sport identified by the URL parameter. Writes an error response
+// and returns nil if it does not exist.
+func (app *application) ladderSport(w http.ResponseWriter, r *http.Request) *models.Sport {
+	sportID, err := strconv.Atoi(chi.URLParam(r, "sportID"))
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return nil
+	}
+
+	sport, err := app.models.Sport.Get(r.Context(), sportID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.renderError(w, r, http.StatusNotFound, "")
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return nil
+	}
+
+	return sport
+}
+
+func ladderURL(sportID int) string {
+	return fmt.Sprintf("/ladders/%d", sportID)
+}
+
+type ladderData struct {
+	Sport          *models.Sport
+	Standings      []*models.LadderRung
+	Challenges     []*models.Challenge
+	UserID         int
+	UserPosition   int
+	ChallengeRange int
+}
+
+// Reports whether the session user may challenge the player at position
+func (d ladderData) CanChallenge(position int) bool {
+	diff := d.UserPosition - position
+
+	return d.UserPosition != 0 && diff >= 1 && diff <= d.ChallengeRange
+}
+
+func (app *application) handleLadderGet(w http.ResponseWriter, r *http.Request) {
+	sport := app.ladderSport(w, r)
+	if sport == nil {
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	standings, err := app.models.Ladder.Standings(r.Context(), sport.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	position := 0
+	for _, l := range standings {
+		if l.UserID == suid {
+			position = l.Position
+			break
+		}
+	}
+
+	challenges, err := app.models.Ladder.Challenges(r.Context(), sport.ID, suid)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	data := ladderData{
+		Sport:          sport,
+		Standings:      standings,
+		Challenges:     challenges,
+		UserID:         suid,
+		UserPosition:   position,
+		ChallengeRange: models.LadderChallengeRange,
+	}
+
+	app.render(w, r, http.StatusOK, "ladder.html", data)
+}
+
+func (app *application) handleLadderJoinPost(w http.ResponseWriter, r *http.Request) {
+	sport := app.ladderSport(w, r)
+	if sport == nil {
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	err = app.models.Ladder.Join(r.Context(), sport.ID, suid)
+	if err != nil && !errors.Is(err, models.ErrDuplicateMember) {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	http.Redirect(w, r, ladderURL(sport.ID), http.StatusSeeOther)
+}
+
+func (app *application) handleLadderLeavePost(w http.ResponseWriter, r *http.Request) {
+	sport := app.ladderSport(w, r)
+	if sport == nil {
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	err = app.models.Ladder.Leave(r.Context(), sport.ID, suid)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.renderError(w, r, http.StatusBadRequest, "")
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	f := FlashMessage{
+		Type:    FlashSuccess,
+		Message: "Successfully left ladder",
+	}
+	app.flash(r, f)
+
+	http.Redirect(w, r, ladderURL(sport.ID), http.StatusSeeOther)
+}
+
+func (app *application) handleLadderChallengePost(w http.ResponseWriter, r *http.Request) {
+	sport := app.ladderSport(w, r)
+	if sport == nil {
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	opponentID, err := strconv.Atoi(r.Form.Get("opponent"))
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	_, err = app.models.Ladder.Challenge(r.Context(), sport.ID, suid, opponentID)
+	if err != nil {
+		var f FlashMessage
+		switch {
+		case errors.Is(err, models.ErrNoRecord):
+			app.renderError(w, r, http.StatusBadRequest, "")
+
+			return
+		case errors.Is(err, models.ErrInvalidChallenge):
+			f = FlashMessage{
+				Type:    FlashError,
+				Message: fmt.Sprintf("Unable to challenge: you may only challenge players up to %d rungs above you.", models.LadderChallengeRange),
+			}
+		case errors.Is(err, models.ErrActiveChallenge):
+			f = FlashMessage{
+				Type:    FlashError,
+				Message: "Unable to challenge: one of you already has an open challenge.",
+			}
+		default:
+			app.serverError(w, r, err)
+
+			return
+		}
+		app.flash(r, f)
+
+		http.Redirect(w, r, ladderURL(sport.ID), http.StatusSeeOther)
+
+		return
+	}
+
+	f := FlashMessage{
+		Type:    FlashSuccess,
+		Message: "Challenge sent. Report the score once you have played.",
+	}
+	app.flash(r, f)
+
+	http.Redirect(w, r, ladderURL(sport.ID), http.StatusSeeOther)
+}
+
+type challengeReportForm struct {
+	score string
+	validator.Validator
+}
+
+func (app *application) handleLadderChallengeReportPost(w http.ResponseWriter, r *http.Request) {
+	sport := app.ladderSport(w, r)
+	if sport == nil {
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	challengeID, err := strconv.Atoi(chi.URLParam(r, "challengeID"))
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	challenges, err := app.models.Ladder.Challenges(r.Context(), sport.ID, suid)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	var challenge *models.Challenge
+	for _, c := range challenges {
+		if c.ID == challengeID {
+			challenge = c
+			break
+		}
+	}
+
+	if challenge == nil || challenge.IsReported() {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	form := challengeReportForm{
+		score: r.Form.Get("score"),
+	}
+
+	// Scores are entered from the challenger's perspective
+	won := false
+	sets, err := models.ParseMatchSets(form.score)
+	if err == nil {
+		won, err = models.ValidateMatchSets(sport.Name, sets)
+	}
+
+	if err != nil {
+		form.Validate(false, "invalid score: "+err.Error())
+	}
+
+	if !form.IsValid() {
+		validationError(w, form.Validator)
+
+		return
+	}
+
+	winnerID := challenge.OpponentID
+	if won {
+		winnerID = challenge.ChallengerID
+	}
+
+	err = app.models.Ladder.Report(r.Context(), sport.ID, challengeID, suid, winnerID, form.score)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.renderError(w, r, http.StatusBadRequest, "")
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	f := FlashMessage{
+		Type:    FlashInfo,
+		Message: "Score reported. Your opponent must confirm it before the ladder is updated.",
+	}
+	app.flash(r, f)
+
+	http.Redirect(w, r, ladderURL(sport.ID), http.StatusSeeOther)
+}
+
+func (app *application) handleLadderChallengeConfirmPost(w http.ResponseWriter, r *http.Request) {
+	sport := app.ladderSport(w, r)
+	if sport == nil {
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	challengeID, err := strconv.Atoi(chi.URLParam(r, "challengeID"))
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	err = app.models.Ladder.Confirm(r.Context(), sport.ID, challengeID, suid)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.renderError(w, r, http.StatusBadRequest, "")
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	f := FlashMessage{
+		Type:    FlashSuccess,
+		Message: "Result confirmed. The ladder has been updated.",
+	}
+	app.flash(r, f)
+
+	http.Redirect(w, r, ladderURL(sport.ID), http.StatusSeeOther)
+}
+
+func (app *application) handleLadderChallengeRejectPost(w http.ResponseWriter, r *http.Request) {
+	sport := app.ladderSport(w, r)
+	if sport == nil {
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	challengeID, err := strconv.Atoi(chi.URLParam(r, "challengeID"))
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	err = app.models.Ladder.Reject(r.Context(), sport.ID, challengeID, suid)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.renderError(w, r, http.StatusBadRequest, "")
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	http.Redirect(w, r, ladderURL(sport.ID), http.StatusSeeOther)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/micahco/racket-connections/internal/crypto"
+	"github.com/micahco/racket-connections/internal/models"
+	"github.com/micahco/racket-connections/internal/validator"
+)
+
+type authLinkForm struct {
+	email string
+	validator.Validator
+}
+
+func (app *application) handleAuthLinkPost(w http.ResponseWriter, r *http.Request) {
+	if app.isAuthenticated(r) {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	form := authLinkForm{email: r.Form.Get("email")}
+
+	form.Validate(validator.NotBlank(form.email), "invalid email: cannot be blank")
+	form.Validate(validator.Matches(form.email, validator.EmailRX), "invalid email: must be a valid email address")
+	form.Validate(validator.MaxChars(form.email, 254), "invalid email: must be no more than 254 characters long")
+
+	if !form.IsValid() {
+		validationError(w, form.Validator)
+
+		return
+	}
+
+	if !app.allowAttempt(w, r, "link", form.email) {
+		return
+	}
+
+	// Consistent flash message
+	f := FlashMessage{
+		Type:    FlashInfo,
+		Message: "A login link has been sent to the email address provided. Please check your junk folder.",
+	}
+
+	userID, err := app.models.User.GetIDByEmail(r.Context(), form.email)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.flash(r, f)
+
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	token, err := crypto.GenerateRandomString(32)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	err = app.models.LoginToken.Insert(r.Context(), crypto.HashToken(token), userID)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	// Create link reference to auth endpoint
+	ref, err := url.Parse("/auth/link")
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	// Set token query
+	q := ref.Query()
+	q.Set("token", token)
+	ref.RawQuery = q.Encode()
+
+	link := app.baseURL.ResolveReference(ref)
+
+	err = app.enqueueEmail(r.Context(), form.email, "login_link.tmpl", link.String())
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	app.flash(r, f)
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+type authLinkData struct {
+	Token string
+}
+
+// Asks the user to confirm the login so that email scanners that follow the
+// link do not consume the token.
+func (app *application) handleAuthLinkGet(w http.ResponseWriter, r *http.Request) {
+	if app.isAuthenticated(r) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		unauthorizedError(w)
+
+		return
+	}
+
+	data := authLinkData{
+		Token: token,
+	}
+
+	app.render(w, r, http.StatusOK, "auth-link.html", data)
+}
+
+func (app *application) handleAuthLinkLoginPost(w http.ResponseWriter, r *http.Request) {
+	if app.isAuthenticated(r) {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	token := r.Form.Get("token")
+	if token == "" {
+		unauthorizedError(w)
+
+		return
+	}
+
+	userID, err := app.models.LoginToken.Consume(r.Context(), crypto.HashToken(token))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			unauthorizedError(w)
+		} else if errors.Is(err, models.ErrExpiredVerification) {
+			f := FlashMessage{
+				Type:    FlashError,
+				Message: "Expired login link. Please request a new one.",
+			}
+			app.flash(r, f)
+
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	// The link replaces the password, not the second factor
+	hasTOTP, err := app.models.TOTP.Exists(r.Context(), userID)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	if hasTOTP {
+		err = app.beginTwoFactor(r, userID)
+		if err != nil {
+			app.serverError(w, r, err)
+
+			return
+		}
+
+		http.Redirect(w, r, "/auth/2fa", http.StatusSeeOther)
+
+		return
+	}
+
+	err = app.login(r, userID)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
@@ -4,7 +4,8 @@ import (
 	"context"
 	"encoding/gob"
 	"flag"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/mail"
 	"net/url"
@@ -15,51 +16,64 @@ import (
 	"github.com/alexedwards/scs/v2"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
+	"github.com/micahco/racket-connections/internal/crypto"
 	"github.com/micahco/racket-connections/internal/env"
 	"github.com/micahco/racket-connections/internal/mailer"
 	"github.com/micahco/racket-connections/internal/models"
+	"golang.org/x/crypto/bcrypt"
 )
 
+const defaultQueryTimeout = 5 * time.Second
+
 func main() {
 	// Parse CLI flags
 	dev := flag.Bool("dev", false, "Development mode")
 	port := flag.String("port", "8080", "Listening address")
+	logFormat := flag.String("log-format", "json", "Log output format (text|json)")
 	flag.Parse()
 
-	// Loggers
-	errorLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
-	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
+	// Structured logger
+	logger, err := newLogger(os.Stdout, *logFormat, *dev)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
 	// Load .env for development
 	if *dev {
 		err := godotenv.Load()
 		if err != nil {
-			errorLog.Fatal("Error loading .env file")
+			logger.Error("Error loading .env file")
+			os.Exit(1)
 		}
 	}
 
 	// Create base URL
 	rawURL, err := env.Get("RC_BASE_URL")
 	if err != nil {
-		errorLog.Fatal(err)
+		logger.Error(err.Error())
+		os.Exit(1)
 	}
 
 	baseURL, err := url.Parse(rawURL)
 	if err != nil {
-		errorLog.Fatal(err)
+		logger.Error(err.Error())
+		os.Exit(1)
 	}
 
 	// PostgreSQL
 	pool, err := newPool()
 	if err != nil {
-		errorLog.Fatal(err)
+		logger.Error(err.Error())
+		os.Exit(1)
 	}
-	defer pool.Close()
+	defer pool.Close() // closed last, after background jobs have drained
 
 	// HTML template cache
 	tc, err := newTemplateCache()
 	if err != nil {
-		errorLog.Fatal(err)
+		logger.Error(err.Error())
+		os.Exit(1)
 	}
 
 	// Session manager
@@ -67,38 +81,93 @@ func main() {
 	sm.Store = pgxstore.New(pool)
 	sm.Lifetime = 12 * time.Hour
 
-	// SMTP mailer
+	// Mailer
 	m, err := newMailer()
 	if err != nil {
-		errorLog.Fatal(err)
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	// OpenID Connect single sign-on
+	op, err := newOIDCProvider(baseURL)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
 	}
 
+	// Password hasher
+	ph, err := newPasswordHasher()
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	// Queries still running after the timeout are cancelled
+	queryTimeout, err := env.GetDuration("RC_DB_QUERY_TIMEOUT", defaultQueryTimeout)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	if queryTimeout <= 0 {
+		logger.Error("invalid query timeout", "timeout", queryTimeout)
+		os.Exit(1)
+	}
+
+	// Prometheus metrics are served on a separate listen address when one is
+	// configured, otherwise on the main router behind a bearer token
+	metricsAddr := env.GetOr("RC_METRICS_ADDR", "")
+	metricsToken := env.GetOr("RC_METRICS_TOKEN", "")
+
+	// Set to the header a trusted proxy puts the client address in, such as
+	// Fly-Client-IP on Fly.io, so rate limits apply per client
+	clientIPHeader := env.GetOr("RC_CLIENT_IP_HEADER", "")
+
 	// New app
 	app := &application{
 		isDevelopment:  *dev,
-		errorLog:       errorLog,
-		infoLog:        infoLog,
+		logger:         logger,
 		baseURL:        baseURL,
-		models:         models.New(pool),
+		models:         models.New(pool, ph, queryTimeout),
 		templateCache:  tc,
 		sessionManager: sm,
 		mailer:         m,
+		oidc:           op,
+		metricsToken:   metricsToken,
+		clientIPHeader: clientIPHeader,
+		shutdown:       make(chan struct{}),
+		outbox:         make(chan struct{}, 1),
 	}
+	app.metrics = newMetrics(pool, app.models)
 
 	// Required to encode/decode session flash messages
 	gob.Register(FlashMessage{})
 
+	// Background jobs
+	app.purgeVerifications()
+	app.purgeDeletedAccounts()
+	app.purgeEmails()
+	app.sendEmails()
+
 	// Listen and serve
 	srv := &http.Server{
 		Addr:     ":" + *port,
-		ErrorLog: errorLog,
+		ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelError),
 		Handler:  app.routes(),
 	}
 
-	infoLog.Println("listening on", *port)
-	err = srv.ListenAndServe()
+	if metricsAddr != "" {
+		app.serveMetrics(metricsAddr)
+	}
+
+	logger.Info("listening", "port", *port)
+	err = app.serve(srv)
 	if err != nil {
-		errorLog.Fatal(err)
+		logger.Error(err.Error())
+
+		// os.Exit skips the deferred close
+		pool.Close()
+		os.Exit(1)
 	}
 }
 
@@ -127,30 +196,91 @@ func newPool() (*pgxpool.Pool, error) {
 }
 
 func newMailer() (*mailer.Mailer, error) {
-	host, err := env.Get("RC_SMTP_HOST")
+	t, err := newMailTransport()
 	if err != nil {
 		return nil, err
 	}
 
-	port, err := env.Get("RC_SMTP_PORT")
-	if err != nil {
-		return nil, err
-	}
+	m := mailer.New(t, &mail.Address{
+		Name:    "Racket Connections",
+		Address: "no-reply@cowell.dev",
+	})
 
-	user, err := env.Get("RC_SMTP_USER")
-	if err != nil {
-		return nil, err
-	}
+	return m, nil
+}
 
-	pass, err := env.Get("RC_SMTP_PASS")
-	if err != nil {
-		return nil, err
+// Configures how email is delivered. Development and staging can write .eml
+// files to a directory instead of sending them.
+func newMailTransport() (mailer.Transport, error) {
+	switch transport := env.GetOr("RC_MAIL_TRANSPORT", "smtp"); transport {
+	case "smtp":
+		host, err := env.Get("RC_SMTP_HOST")
+		if err != nil {
+			return nil, err
+		}
+
+		port, err := env.Get("RC_SMTP_PORT")
+		if err != nil {
+			return nil, err
+		}
+
+		user, err := env.Get("RC_SMTP_USER")
+		if err != nil {
+			return nil, err
+		}
+
+		pass, err := env.Get("RC_SMTP_PASS")
+		if err != nil {
+			return nil, err
+		}
+
+		return mailer.NewSMTPTransport(host, port, user, pass)
+	case "file":
+		return mailer.NewFileTransport(env.GetOr("RC_MAIL_DIR", "tmp/mail"))
+	case "memory":
+		return mailer.NewMemoryTransport(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport: %s", transport)
 	}
+}
 
-	return mailer.New(host, port, user, pass,
-		&mail.Address{
-			Name:    "Racket Connections",
-			Address: "no-reply@cowell.dev",
-		},
-	)
+// Configures the hasher used for new passwords. Existing hashes made by
+// another algorithm or with weaker parameters are upgraded on login.
+func newPasswordHasher() (crypto.PasswordHasher, error) {
+	switch alg := env.GetOr("RC_PASSWORD_HASHER", "bcrypt"); alg {
+	case "bcrypt":
+		cost, err := env.GetInt("RC_BCRYPT_COST", bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+
+		if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("invalid bcrypt cost: %d", cost)
+		}
+
+		return &crypto.BcryptHasher{Cost: cost}, nil
+	case "argon2id":
+		h := crypto.NewArgon2idHasher()
+
+		memory, err := env.GetInt("RC_ARGON2_MEMORY", int(h.Memory))
+		if err != nil {
+			return nil, err
+		}
+
+		iterations, err := env.GetInt("RC_ARGON2_ITERATIONS", int(h.Iterations))
+		if err != nil {
+			return nil, err
+		}
+
+		if memory < 8*int(h.Parallelism) || iterations < 1 {
+			return nil, fmt.Errorf("invalid argon2id parameters: m=%d, t=%d", memory, iterations)
+		}
+
+		h.Memory = uint32(memory)
+		h.Iterations = uint32(iterations)
+
+		return h, nil
+	default:
+		return nil, fmt.Errorf("unknown password hasher: %s", alg)
+	}
 }
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/micahco/racket-connections/internal/models"
+	"github.com/micahco/racket-connections/internal/validator"
+)
+
+type newMatchData struct {
+	OpponentID   int
+	OpponentName string
+	SportID      int
+	Sports       []*models.Sport
+	Today        string
+}
+
+func (app *application) handleMatchesNewGet(w http.ResponseWriter, r *http.Request) {
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	opponentID, err := strconv.Atoi(r.URL.Query().Get("opponent"))
+	if err != nil || opponentID == suid {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	exists, err := app.models.User.Exists(r.Context(), opponentID)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	if !exists {
+		app.renderError(w, r, http.StatusNotFound, "")
+
+		return
+	}
+
+	opponent, err := app.models.User.GetProfile(r.Context(), opponentID)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	sports, err := app.models.Sport.All(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	// Preselect the sport when reporting from a post
+	sportID, _ := strconv.Atoi(r.URL.Query().Get("sport"))
+
+	data := newMatchData{
+		OpponentID:   opponentID,
+		OpponentName: opponent.Name,
+		SportID:      sportID,
+		Sports:       sports,
+		Today:        time.Now().Format(time.DateOnly),
+	}
+
+	app.render(w, r, http.StatusOK, "matches-new.html", data)
+}
+
+type newMatchForm struct {
+	opponent int
+	sport    int
+	playedOn string
+	score    string
+	validator.Validator
+}
+
+func (app *application) handleMatchesNewPost(w http.ResponseWriter, r *http.Request) {
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	opponentID, err := strconv.Atoi(r.Form.Get("opponent"))
+	if err != nil || opponentID == suid {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	sportID, err := strconv.Atoi(r.Form.Get("sport"))
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	form := newMatchForm{
+		opponent: opponentID,
+		sport:    sportID,
+		playedOn: r.Form.Get("played-on"),
+		score:    r.Form.Get("score"),
+	}
+
+	exists, err := app.models.User.Exists(r.Context(), form.opponent)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	sport, err := app.models.Sport.Get(r.Context(), form.sport)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	form.Validate(exists, "invalid opponent")
+	form.Validate(sport != nil, "invalid sport")
+
+	playedOn, err := time.Parse(time.DateOnly, form.playedOn)
+	form.Validate(err == nil, "invalid date: must be a valid date")
+	form.Validate(!playedOn.After(time.Now()), "invalid date: cannot be in the future")
+
+	won := false
+	sets, err := models.ParseMatchSets(form.score)
+	if err == nil && sport != nil {
+		won, err = models.ValidateMatchSets(sport.Name, sets)
+	}
+
+	if err != nil {
+		form.Validate(false, "invalid score: "+err.Error())
+	}
+
+	if !form.IsValid() {
+		validationError(w, form.Validator)
+
+		return
+	}
+
+	winnerID := form.opponent
+	if won {
+		winnerID = suid
+	}
+
+	_, err = app.models.Match.Insert(r.Context(), sport.ID, suid, form.opponent, winnerID, playedOn, sets)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	f := FlashMessage{
+		Type:    FlashInfo,
+		Message: "Match reported. It will appear in your history once your opponent confirms it.",
+	}
+	app.flash(r, f)
+
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+func (app *application) handleMatchesIdConfirmPost(w http.ResponseWriter, r *http.Request) {
+	matchID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	err = app.models.Match.Confirm(r.Context(), matchID, suid)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.renderError(w, r, http.StatusBadRequest, "")
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	f := FlashMessage{
+		Type:    FlashSuccess,
+		Message: "Match confirmed",
+	}
+	app.flash(r, f)
+
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+func (app *application) handleMatchesIdRejectPost(w http.ResponseWriter, r *http.Request) {
+	matchID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	err = app.models.Match.Reject(r.Context(), matchID, suid)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.renderError(w, r, http.StatusBadRequest, "")
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	f := FlashMessage{
+		Type:    FlashSuccess,
+		Message: "Match removed",
+	}
+	app.flash(r, f)
+
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/micahco/racket-connections/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	emails   *prometheus.CounterVec
+}
+
+func newMetrics(pool *pgxpool.Pool, m models.Models) *metrics {
+	reg := prometheus.NewRegistry()
+
+	mt := &metrics{
+		registry: reg,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by route pattern, method and status code.",
+		}, []string{"route", "method", "code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route pattern and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		emails: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "emails_sent_total",
+			Help: "Emails sent by template and result.",
+		}, []string{"template", "result"}),
+	}
+
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		mt.requests,
+		mt.latency,
+		mt.emails,
+		newStatsCollector(m),
+	)
+
+	// Handlers are tested without a pool
+	if pool != nil {
+		reg.MustRegister(newPoolCollector(pool))
+	}
+
+	return mt
+}
+
+// Records the outcome of sending an email
+func (mt *metrics) emailSent(templateFile string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+
+	mt.emails.WithLabelValues(templateFile, result).Inc()
+}
+
+// Records request counts and latency by chi route pattern, which is only
+// known once routing has completed
+func (app *application) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if p := rctx.RoutePattern(); p != "" {
+				route = strings.TrimSuffix(p, "/*")
+			}
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		app.metrics.requests.WithLabelValues(route, r.Method, strconv.Itoa(status)).Inc()
+		app.metrics.latency.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+func (app *application) handleMetrics() http.Handler {
+	return promhttp.HandlerFor(app.metrics.registry, promhttp.HandlerOpts{
+		ErrorLog: slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+	})
+}
+
+// Requires the scraper to present the bearer token
+func requireMetricsToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			unauthorizedError(w)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquireCount    *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	emptyAcquire    *prometheus.Desc
+	canceledAcquire *prometheus.Desc
+	acquiredConns   *prometheus.Desc
+	idleConns       *prometheus.Desc
+	totalConns      *prometheus.Desc
+	maxConns        *prometheus.Desc
+}
+
+func newPoolCollector(pool *pgxpool.Pool) *poolCollector {
+	return &poolCollector{
+		pool: pool,
+		acquireCount: prometheus.NewDesc("pgxpool_acquire_total",
+			"Successful connection acquires from the pool.", nil, nil),
+		acquireDuration: prometheus.NewDesc("pgxpool_acquire_duration_seconds_total",
+			"Time spent acquiring connections from the pool.", nil, nil),
+		emptyAcquire: prometheus.NewDesc("pgxpool_empty_acquire_total",
+			"Acquires that waited for a connection because the pool was empty.", nil, nil),
+		canceledAcquire: prometheus.NewDesc("pgxpool_canceled_acquire_total",
+			"Acquires canceled by their context.", nil, nil),
+		acquiredConns: prometheus.NewDesc("pgxpool_acquired_connections",
+			"Connections currently in use.", nil, nil),
+		idleConns: prometheus.NewDesc("pgxpool_idle_connections",
+			"Connections currently idle.", nil, nil),
+		totalConns: prometheus.NewDesc("pgxpool_total_connections",
+			"Connections currently open.", nil, nil),
+		maxConns: prometheus.NewDesc("pgxpool_max_connections",
+			"Maximum size of the pool.", nil, nil),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquire, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquire, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+}
+
+// Queries business gauges from the database on each scrape
+type statsCollector struct {
+	models models.Models
+
+	sessions *prometheus.Desc
+	users    *prometheus.Desc
+	posts    *prometheus.Desc
+}
+
+func newStatsCollector(m models.Models) *statsCollector {
+	return &statsCollector{
+		models: m,
+		sessions: prometheus.NewDesc("active_sessions",
+			"Unexpired sessions of logged in users.", nil, nil),
+		users: prometheus.NewDesc("users",
+			"Registered users.", nil, nil),
+		posts: prometheus.NewDesc("open_posts",
+			"Open posts by sport.", []string{"sport"}, nil),
+	}
+}
+
+// Described explicitly since collecting queries the database
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sessions
+	ch <- c.users
+	ch <- c.posts
+}
+
+// Collect has no request context, so queries are bounded by the models'
+// timeout alone
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx := context.Background()
+
+	n, err := c.models.Session.Active(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.sessions, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.sessions, prometheus.GaugeValue, float64(n))
+	}
+
+	n, err = c.models.User.Count(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.users, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.users, prometheus.GaugeValue, float64(n))
+	}
+
+	counts, err := c.models.Post.CountBySport(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.posts, err)
+
+		return
+	}
+
+	for _, sc := range counts {
+		ch <- prometheus.MustNewConstMetric(c.posts, prometheus.GaugeValue, float64(sc.Count), sc.Sport)
+	}
+}
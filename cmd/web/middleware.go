@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/justinas/nosurf"
+	"github.com/micahco/racket-connections/internal/crypto"
 )
 
 func (app *application) recovery(next http.Handler) http.Handler {
@@ -23,11 +26,43 @@ func (app *application) recovery(next http.Handler) http.Handler {
 	})
 }
 
+// Identifies each request with a random ID, which is added to the request
+// context and the X-Request-ID response header
+func requestIDs(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := crypto.GenerateRandomString(12)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("X-Request-ID", id)
+
+		ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func (app *application) logRequests(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		app.infoLog.Printf("%s - %s %s %s", r.RemoteAddr, r.Proto, r.Method, r.URL.RequestURI())
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		app.logger.InfoContext(r.Context(), "request",
+			"ip", r.RemoteAddr,
+			"proto", r.Proto,
+			"method", r.Method,
+			"uri", r.URL.RequestURI(),
+			"status", status,
+			"bytes", ww.BytesWritten(),
+			"duration", time.Since(start))
 	})
 }
 
@@ -46,7 +81,10 @@ func secureHeaders(next http.Handler) http.Handler {
 
 func (app *application) csrfFailureHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		app.infoLog.Printf("CRSF FAILURE: %s %s", r.Method, r.URL.RequestURI())
+		app.logger.WarnContext(r.Context(), "csrf failure",
+			"method", r.Method,
+			"uri", r.URL.RequestURI(),
+			"reason", nosurf.Reason(r))
 
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 	})
@@ -73,13 +111,19 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		exists, err := app.models.User.Exists(id)
+		exists, err := app.models.User.Exists(r.Context(), id)
 		if err != nil {
 			app.serverError(w, r, err)
 			return
 		}
 
 		if exists {
+			err = app.models.Session.Touch(r.Context(), app.sessionManager.Token(r.Context()), app.clientIP(r))
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+
 			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
 			r = r.WithContext(ctx)
 		}
@@ -100,3 +144,26 @@ func (app *application) requireAuthentication(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+func (app *application) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suid, err := app.getSessionUserID(r)
+		if err != nil {
+			unauthorizedError(w)
+			return
+		}
+
+		isAdmin, err := app.models.Admin.Exists(r.Context(), suid)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if !isAdmin {
+			app.renderError(w, r, http.StatusForbidden, "")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/micahco/racket-connections/internal/assert"
+)
+
+func TestLogRequests(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger, err := newLogger(&buf, "json", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &application{logger: logger}
+
+	var ctxID string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxID = requestID(r.Context())
+
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	})
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/teapot?brew=1", nil)
+
+	requestIDs(app.logRequests(next)).ServeHTTP(rr, r)
+
+	id := rr.Header().Get("X-Request-ID")
+	assert.Equal(t, id != "", true)
+	assert.Equal(t, ctxID, id)
+
+	var entry struct {
+		Msg       string
+		URI       string
+		Status    int
+		Bytes     int
+		RequestID string `json:"request_id"`
+	}
+
+	err = json.Unmarshal(buf.Bytes(), &entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, entry.Msg, "request")
+	assert.Equal(t, entry.URI, "/teapot?brew=1")
+	assert.Equal(t, entry.Status, http.StatusTeapot)
+	assert.Equal(t, entry.Bytes, len("short and stout"))
+	assert.Equal(t, entry.RequestID, id)
+}
+
+func TestRequireMetricsToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{
+			name:          "Valid",
+			authorization: "Bearer secret",
+			want:          http.StatusOK,
+		},
+		{
+			name:          "Wrong token",
+			authorization: "Bearer secrets",
+			want:          http.StatusUnauthorized,
+		},
+		{
+			name:          "Wrong scheme",
+			authorization: "Basic secret",
+			want:          http.StatusUnauthorized,
+		},
+		{
+			name:          "Missing",
+			authorization: "",
+			want:          http.StatusUnauthorized,
+		},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			r.Header.Set("Authorization", tt.authorization)
+
+			requireMetricsToken("secret", next).ServeHTTP(rr, r)
+
+			assert.Equal(t, rr.Code, tt.want)
+		})
+	}
+}
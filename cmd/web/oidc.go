@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/micahco/racket-connections/internal/crypto"
+	"github.com/micahco/racket-connections/internal/env"
+	"github.com/micahco/racket-connections/internal/models"
+	"github.com/micahco/racket-connections/internal/validator"
+	"golang.org/x/oauth2"
+)
+
+// OpenID Connect relying party for single sign-on
+type oidcProvider struct {
+	issuer   string
+	verifier *oidc.IDTokenVerifier
+	config   oauth2.Config
+}
+
+type oidcClaims struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// Discovers the provider configured by the environment. Returns nil if single
+// sign-on is not configured.
+func newOIDCProvider(baseURL *url.URL) (*oidcProvider, error) {
+	var notSet *env.EnvVarNotSetError
+
+	issuer, err := env.Get("RC_OIDC_ISSUER")
+	if errors.As(err, &notSet) || issuer == "" {
+		return nil, nil
+	}
+
+	clientID, err := env.Get("RC_OIDC_CLIENT_ID")
+	if err != nil {
+		return nil, err
+	}
+
+	clientSecret, err := env.Get("RC_OIDC_CLIENT_SECRET")
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := url.Parse("/auth/oidc/callback")
+	if err != nil {
+		return nil, err
+	}
+
+	redirectURL := baseURL.ResolveReference(ref).String()
+
+	return discoverOIDCProvider(context.Background(), issuer, clientID, clientSecret, redirectURL)
+}
+
+func discoverOIDCProvider(ctx context.Context, issuer, clientID, clientSecret, redirectURL string) (*oidcProvider, error) {
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &oidcProvider{
+		issuer:   issuer,
+		verifier: provider.Verifier(&oidc.Config{ClientID: clientID}),
+		config: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  redirectURL,
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+	}
+
+	return p, nil
+}
+
+func (p *oidcProvider) authCodeURL(state, nonce, verifier string) string {
+	return p.config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchanges the authorization code and returns the claims of the verified ID
+// token.
+func (p *oidcProvider) exchange(ctx context.Context, code, verifier, nonce string) (*oidcClaims, error) {
+	token, err := p.config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("oidc: token response missing id_token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if idToken.Nonce != nonce {
+		return nil, errors.New("oidc: nonce mismatch")
+	}
+
+	var claims oidcClaims
+	err = idToken.Claims(&claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+func (app *application) handleAuthOIDCGet(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.renderError(w, r, http.StatusNotFound, "")
+
+		return
+	}
+
+	if app.isAuthenticated(r) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+
+		return
+	}
+
+	state, err := crypto.GenerateRandomString(32)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	nonce, err := crypto.GenerateRandomString(32)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	verifier := oauth2.GenerateVerifier()
+
+	app.sessionManager.Put(r.Context(), oidcStateSessionKey, state)
+	app.sessionManager.Put(r.Context(), oidcNonceSessionKey, nonce)
+	app.sessionManager.Put(r.Context(), oidcVerifierSessionKey, verifier)
+
+	http.Redirect(w, r, app.oidc.authCodeURL(state, nonce, verifier), http.StatusFound)
+}
+
+func (app *application) handleAuthOIDCCallbackGet(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.renderError(w, r, http.StatusNotFound, "")
+
+		return
+	}
+
+	state := app.sessionManager.PopString(r.Context(), oidcStateSessionKey)
+	nonce := app.sessionManager.PopString(r.Context(), oidcNonceSessionKey)
+	verifier := app.sessionManager.PopString(r.Context(), oidcVerifierSessionKey)
+
+	q := r.URL.Query()
+	if state == "" || q.Get("state") != state {
+		unauthorizedError(w)
+
+		return
+	}
+
+	if q.Get("error") != "" {
+		f := FlashMessage{
+			Type:    FlashError,
+			Message: "Single sign-on failed. Please try again.",
+		}
+		app.flash(r, f)
+
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+
+		return
+	}
+
+	claims, err := app.oidc.exchange(r.Context(), q.Get("code"), verifier, nonce)
+	if err != nil {
+		app.logger.WarnContext(r.Context(), "oidc exchange failed", "error", err)
+		unauthorizedError(w)
+
+		return
+	}
+
+	if !claims.EmailVerified || !validator.PermittedEmailDomain(claims.Email, "oregonstate.edu") {
+		app.renderError(w, r, http.StatusForbidden, "Single sign-on requires a verified OSU email address.")
+
+		return
+	}
+
+	userID, err := app.models.Identity.GetUserID(r.Context(), app.oidc.issuer, claims.Subject)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	// Link to an existing account with the same email
+	if userID == 0 {
+		userID, err = app.models.User.GetIDByEmail(r.Context(), claims.Email)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, r, err)
+
+			return
+		}
+
+		if userID != 0 {
+			err = app.models.Identity.Insert(r.Context(), app.oidc.issuer, claims.Subject, userID)
+			if err != nil {
+				app.serverError(w, r, err)
+
+				return
+			}
+		}
+	}
+
+	// Collect the rest of the profile before creating an account
+	if userID == 0 {
+		err = app.sessionManager.RenewToken(r.Context())
+		if err != nil {
+			app.serverError(w, r, err)
+
+			return
+		}
+
+		app.sessionManager.Put(r.Context(), oidcSubjectSessionKey, claims.Subject)
+		app.sessionManager.Put(r.Context(), oidcEmailSessionKey, claims.Email)
+		app.sessionManager.Put(r.Context(), oidcNameSessionKey, claims.Name)
+
+		http.Redirect(w, r, "/auth/oidc/register", http.StatusSeeOther)
+
+		return
+	}
+
+	hasTOTP, err := app.models.TOTP.Exists(r.Context(), userID)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	if hasTOTP {
+		err = app.beginTwoFactor(r, userID)
+		if err != nil {
+			app.serverError(w, r, err)
+
+			return
+		}
+
+		http.Redirect(w, r, "/auth/2fa", http.StatusSeeOther)
+
+		return
+	}
+
+	err = app.login(r, userID)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+type authOIDCRegisterData struct {
+	Name           string
+	Email          string
+	ContactMethods []*models.ContactMethod
+}
+
+func (app *application) handleAuthOIDCRegisterGet(w http.ResponseWriter, r *http.Request) {
+	email := app.sessionManager.GetString(r.Context(), oidcEmailSessionKey)
+	if email == "" {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+
+		return
+	}
+
+	m, err := app.models.Contact.Methods(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	data := authOIDCRegisterData{
+		Name:           app.sessionManager.GetString(r.Context(), oidcNameSessionKey),
+		Email:          email,
+		ContactMethods: m,
+	}
+
+	app.render(w, r, http.StatusOK, "auth-oidc-register.html", data)
+}
+
+type authOIDCRegisterForm struct {
+	name          string
+	contactMethod string
+	contactValue  string
+	validator.Validator
+}
+
+func (app *application) handleAuthOIDCRegisterPost(w http.ResponseWriter, r *http.Request) {
+	subject := app.sessionManager.GetString(r.Context(), oidcSubjectSessionKey)
+	email := app.sessionManager.GetString(r.Context(), oidcEmailSessionKey)
+	if app.oidc == nil || subject == "" || email == "" {
+		unauthorizedError(w)
+
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	form := authOIDCRegisterForm{
+		name:          r.Form.Get("name"),
+		contactMethod: r.Form.Get("contact-method"),
+		contactValue:  r.Form.Get("contact-value"),
+	}
+
+	form.Validate(validator.NotBlank(form.name), "invalid name: cannot be blank")
+	form.Validate(validator.NotBlank(form.contactValue), "invalid contact value: cannot be blank")
+
+	switch form.contactMethod {
+	case "email":
+		form.Validate(validator.Matches(form.contactValue, validator.EmailRX), "invalid contact email: must be a valid email address")
+		form.Validate(validator.MaxChars(form.contactValue, 254), "invalid contact email: must be no more than 254 characters long")
+	case "phone":
+		form.Validate(validator.Matches(form.contactValue, validator.PhoneRX), "invalid contact phone: must be a valid phone number")
+	case "other":
+	default:
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	if !form.IsValid() {
+		validationError(w, form.Validator)
+
+		return
+	}
+
+	// The account has no usable password until the user resets it
+	password, err := crypto.GenerateRandomString(32)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	var userID int
+	err = app.models.Tx.Do(r.Context(), func(tx models.Models) error {
+		var err error
+		userID, err = tx.User.Insert(r.Context(), form.name, email, password)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Identity.Insert(r.Context(), app.oidc.issuer, subject, userID)
+		if err != nil {
+			return err
+		}
+
+		methodID, err := tx.Contact.MethodID(r.Context(), form.contactMethod)
+		if err != nil {
+			return err
+		}
+
+		return tx.Contact.Insert(r.Context(), form.contactValue, userID, methodID)
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrDuplicateEmail) {
+			unauthorizedError(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	app.sessionManager.Clear(r.Context())
+	err = app.login(r, userID)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	f := FlashMessage{
+		Type:    FlashSuccess,
+		Message: fmt.Sprintf("Successfully created account for %s. Welcome!", email),
+	}
+	app.flash(r, f)
+
+	http.Redirect(w, r, "/profile/availability", http.StatusSeeOther)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/micahco/racket-connections/internal/assert"
+)
+
+// Minimal OpenID provider that issues an ID token for a single authorization
+// code, verifying the PKCE challenge.
+type mockOIDCProvider struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	code      string
+	challenge string
+	nonce     string
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &mockOIDCProvider{key: key, code: "code"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                m.URL,
+			"authorization_endpoint":                m.URL + "/authorize",
+			"token_endpoint":                        m.URL + "/token",
+			"jwks_uri":                              m.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &m.key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if r.Form.Get("code") != m.code || base64.RawURLEncoding.EncodeToString(sum[:]) != m.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     m.idToken(t),
+		})
+	})
+
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+
+	return m
+}
+
+func (m *mockOIDCProvider) idToken(t *testing.T) string {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: m.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, _ := json.Marshal(map[string]any{
+		"iss":            m.URL,
+		"sub":            "12345",
+		"aud":            "client",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          m.nonce,
+		"email":          "benny@oregonstate.edu",
+		"email_verified": true,
+		"name":           "Benny Beaver",
+	})
+
+	sig, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := sig.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestOIDCExchange(t *testing.T) {
+	mock := newMockOIDCProvider(t)
+	ctx := context.Background()
+
+	p, err := discoverOIDCProvider(ctx, mock.URL, "client", "secret", "http://localhost/auth/oidc/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := url.Parse(p.authCodeURL("state", "nonce", "verifier-verifier-verifier-verifier-verifier"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := authURL.Query()
+	assert.Equal(t, q.Get("code_challenge_method"), "S256")
+	assert.Equal(t, q.Get("state"), "state")
+
+	mock.challenge = q.Get("code_challenge")
+	mock.nonce = q.Get("nonce")
+
+	t.Run("Valid", func(t *testing.T) {
+		claims, err := p.exchange(ctx, "code", "verifier-verifier-verifier-verifier-verifier", "nonce")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, claims.Subject, "12345")
+		assert.Equal(t, claims.Email, "benny@oregonstate.edu")
+		assert.Equal(t, claims.EmailVerified, true)
+		assert.Equal(t, claims.Name, "Benny Beaver")
+	})
+
+	t.Run("Wrong verifier", func(t *testing.T) {
+		_, err := p.exchange(ctx, "code", "wrong-verifier-wrong-verifier-wrong-verifier", "nonce")
+		assert.Equal(t, err != nil, true)
+	})
+
+	t.Run("Wrong nonce", func(t *testing.T) {
+		_, err := p.exchange(ctx, "code", "verifier-verifier-verifier-verifier-verifier", "other")
+		assert.Equal(t, err != nil, true)
+	})
+}
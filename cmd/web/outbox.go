@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/mailer"
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+const (
+	outboxInterval    = 10 * time.Second
+	outboxBatchSize   = 10
+	outboxLease       = 5 * time.Minute
+	maxEmailAttempts  = 8
+	emailBackoffBase  = 30 * time.Second
+	emailBackoffLimit = 6 * time.Hour
+	// Sent and dead-lettered emails are kept this long for the admin page
+	emailRetention = 30 * 24 * time.Hour
+)
+
+// Renders the email and queues it for delivery by the outbox worker
+func (app *application) enqueueEmail(ctx context.Context, recipient, templateFile string, data interface{}) error {
+	err := insertEmail(ctx, app.models, recipient, templateFile, data)
+	if err != nil {
+		return err
+	}
+
+	app.wakeOutbox()
+
+	return nil
+}
+
+// Renders the email into the outbox using the given models, so it can be
+// queued inside a transaction. The caller wakes the outbox once committed.
+func insertEmail(ctx context.Context, m models.Models, recipient, templateFile string, data interface{}) error {
+	msg, err := mailer.Render(recipient, templateFile, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.Email.Insert(ctx, recipient, templateFile, msg.Subject, msg.Body, msg.HTMLBody)
+
+	return err
+}
+
+// Wakes the worker without blocking if it is already awake
+func (app *application) wakeOutbox() {
+	select {
+	case app.outbox <- struct{}{}:
+	default:
+	}
+}
+
+// Returns the delay before the next delivery attempt, or false if the email
+// should be dead-lettered after the given number of failed attempts
+func emailBackoff(attempts int) (time.Duration, bool) {
+	if attempts >= maxEmailAttempts {
+		return 0, false
+	}
+
+	d := emailBackoffBase << (attempts - 1)
+	if d > emailBackoffLimit || d <= 0 {
+		d = emailBackoffLimit
+	}
+
+	return d, true
+}
+
+// Delivers queued emails until shutdown
+func (app *application) sendEmails() {
+	app.worker(func() {
+		ticker := time.NewTicker(outboxInterval)
+		defer ticker.Stop()
+
+		for {
+			app.sendDueEmails()
+
+			select {
+			case <-app.shutdown:
+				return
+			case <-ticker.C:
+			case <-app.outbox:
+			}
+		}
+	})
+}
+
+// Jobs are drained rather than cancelled on shutdown, so queries are only
+// bounded by the models' timeout
+func (app *application) sendDueEmails() {
+	ctx := context.Background()
+
+	emails, err := app.models.Email.Claim(ctx, outboxBatchSize, outboxLease)
+	if err != nil {
+		app.logger.Error(err.Error())
+
+		return
+	}
+
+	for _, e := range emails {
+		msg := &mailer.Message{
+			Recipient: e.Recipient,
+			Subject:   e.Subject,
+			Body:      e.Body,
+		}
+
+		if e.HTMLBody != nil {
+			msg.HTMLBody = *e.HTMLBody
+		}
+
+		messageID, err := app.mailer.Deliver(msg)
+		app.metrics.emailSent(e.Template, err)
+
+		if err == nil {
+			app.logger.Info("sent email",
+				"id", e.ID,
+				"template", e.Template,
+				"message_id", messageID)
+
+			err = app.models.Email.MarkSent(ctx, e.ID, messageID)
+			if err != nil {
+				app.logger.Error(err.Error())
+			}
+
+			continue
+		}
+
+		var next *time.Time
+		if d, ok := emailBackoff(e.Attempts + 1); ok {
+			t := time.Now().Add(d)
+			next = &t
+		}
+
+		app.logger.Warn("email delivery failed",
+			"id", e.ID,
+			"template", e.Template,
+			"attempt", e.Attempts+1,
+			"dead", next == nil,
+			"error", err)
+
+		err = app.models.Email.MarkFailed(ctx, e.ID, err.Error(), next)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+	}
+}
+
+type adminEmailsData struct {
+	Status   string
+	Statuses []string
+	Counts   map[string]int
+	Emails   []*models.Email
+}
+
+const adminEmailsLimit = 100
+
+func (app *application) handleAdminEmailsGet(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = models.EmailDead
+	}
+
+	statuses := []string{models.EmailPending, models.EmailSent, models.EmailDead}
+
+	valid := false
+	for _, s := range statuses {
+		if s == status {
+			valid = true
+			break
+		}
+	}
+
+	if !valid {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	counts, err := app.models.Email.Counts(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	emails, err := app.models.Email.Recent(r.Context(), status, adminEmailsLimit)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	data := adminEmailsData{
+		Status:   status,
+		Statuses: statuses,
+		Counts:   make(map[string]int),
+		Emails:   emails,
+	}
+
+	for _, c := range counts {
+		data.Counts[c.Status] = c.Count
+	}
+
+	app.render(w, r, http.StatusOK, "admin-emails.html", data)
+}
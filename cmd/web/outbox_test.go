@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/assert"
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+func TestEmailBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		want     time.Duration
+		retry    bool
+	}{
+		{
+			name:     "First failure",
+			attempts: 1,
+			want:     emailBackoffBase,
+			retry:    true,
+		},
+		{
+			name:     "Second failure",
+			attempts: 2,
+			want:     2 * emailBackoffBase,
+			retry:    true,
+		},
+		{
+			name:     "Fourth failure",
+			attempts: 4,
+			want:     8 * emailBackoffBase,
+			retry:    true,
+		},
+		{
+			name:     "Dead",
+			attempts: maxEmailAttempts,
+			want:     0,
+			retry:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := emailBackoff(tt.attempts)
+
+			assert.Equal(t, d, tt.want)
+			assert.Equal(t, ok, tt.retry)
+		})
+	}
+}
+
+// Delivered and dead-lettered emails keep no copy of their bodies
+func TestSendDueEmails(t *testing.T) {
+	app, mt := newTestApplication(t)
+
+	ctx := context.Background()
+
+	err := app.enqueueEmail(ctx, testEmail, "login_link.tmpl", "https://racket.example.com/auth/link?token=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadID, err := app.models.Email.Insert(ctx, testEmail, "login_link.tmpl", "Login link", "secret", "<p>secret</p>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.models.Email.MarkFailed(ctx, deadID, "mailbox full", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.sendDueEmails()
+
+	msg := mt.Last(testEmail)
+	assert.Equal(t, msg != nil && strings.Contains(msg.Body, "token=secret"), true)
+
+	for _, status := range []string{models.EmailSent, models.EmailDead} {
+		emails, err := app.models.Email.Recent(ctx, status, 10)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(emails), 1)
+		assert.Equal(t, emails[0].Body, "")
+		assert.Equal(t, emails[0].HTMLBody == nil, true)
+	}
+
+	n, err := app.models.Email.Purge(ctx, time.Now().Add(-emailRetention))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, n, 0)
+
+	n, err = app.models.Email.Purge(ctx, time.Now().Add(time.Minute))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, n, 2)
+}
@@ -36,9 +36,9 @@ func (app *application) handlePostsGet(w http.ResponseWriter, r *http.Request) {
 		Timeslot: make([]models.Timeslot, 0),
 	}
 
-	days, _ := app.models.Timeslot.Days()
-	times, _ := app.models.Timeslot.Times()
-	s, _ := app.models.Sport.All()
+	days, _ := app.models.Timeslot.Days(r.Context())
+	times, _ := app.models.Timeslot.Times(r.Context())
+	s, _ := app.models.Sport.All(r.Context())
 
 	for _, d := range days {
 		for _, t := range times {
@@ -52,7 +52,7 @@ func (app *application) handlePostsGet(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	p, err := app.models.Post.Fetch(q.Sport, q.Timeslot)
+	p, err := app.models.Post.Fetch(r.Context(), q.Sport, q.Timeslot)
 	if err != nil {
 		app.serverError(w, r, err)
 
@@ -80,7 +80,7 @@ func (app *application) handlePostsSyncGet(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	timeslots, err := app.models.Timeslot.User(suid)
+	timeslots, err := app.models.Timeslot.User(r.Context(), suid)
 	if err != nil {
 		app.serverError(w, r, err)
 
@@ -100,12 +100,15 @@ func (app *application) handlePostsPost(w http.ResponseWriter, r *http.Request)
 }
 
 type postData struct {
-	Post      *models.PostDetails
-	Contacts  []*models.UserContact
-	Days      []*models.DayOfWeek
-	Times     []*models.TimeOfDay
-	Timeslots []*models.Timeslot
-	IsOwner   bool
+	Post            *models.PostDetails
+	Contacts        []*models.UserContact
+	Days            []*models.DayOfWeek
+	Times           []*models.TimeOfDay
+	Timeslots       []*models.Timeslot
+	SharedTimeslots []*models.Timeslot
+	Roster          []*models.RosterMember
+	IsOwner         bool
+	IsMember        bool
 }
 
 func (app *application) handlePostsIdGet(w http.ResponseWriter, r *http.Request) {
@@ -123,7 +126,7 @@ func (app *application) handlePostsIdGet(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	p, err := app.models.Post.GetDetails(postID)
+	p, err := app.models.Post.GetDetails(r.Context(), postID)
 	if err != nil {
 		if errors.Is(err, models.ErrNoRecord) {
 			app.renderError(w, r, http.StatusNotFound, "")
@@ -134,14 +137,14 @@ func (app *application) handlePostsIdGet(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	c, err := app.models.Contact.UserContacts(p.UserID)
+	c, err := app.models.Contact.UserContacts(r.Context(), p.UserID)
 	if err != nil {
 		app.serverError(w, r, err)
 
 		return
 	}
 
-	timeslots, err := app.models.Timeslot.User(p.UserID)
+	timeslots, err := app.models.Timeslot.User(r.Context(), p.UserID)
 	if err != nil {
 		app.serverError(w, r, err)
 
@@ -155,16 +158,49 @@ func (app *application) handlePostsIdGet(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	d, _ := app.models.Timeslot.Days()
-	t, _ := app.models.Timeslot.Times()
+	roster, err := app.models.Roster.Post(r.Context(), postID)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	isMember := false
+	for _, m := range roster {
+		if m.UserID == suid {
+			isMember = true
+			break
+		}
+	}
+
+	isOwner := suid == p.UserID
+
+	// Compare the poster's availability with the session user's
+	var shared []*models.Timeslot
+	if !isOwner {
+		userTimeslots, err := app.models.Timeslot.User(r.Context(), suid)
+		if err != nil {
+			app.serverError(w, r, err)
+
+			return
+		}
+
+		shared = models.SharedTimeslots(timeslots, userTimeslots)
+	}
+
+	d, _ := app.models.Timeslot.Days(r.Context())
+	t, _ := app.models.Timeslot.Times(r.Context())
 
 	data := postData{
-		Post:      p,
-		Contacts:  c,
-		Days:      d,
-		Times:     t,
-		Timeslots: timeslots,
-		IsOwner:   suid == p.UserID,
+		Post:            p,
+		Contacts:        c,
+		Days:            d,
+		Times:           t,
+		Timeslots:       timeslots,
+		SharedTimeslots: shared,
+		Roster:          roster,
+		IsOwner:         isOwner,
+		IsMember:        isMember,
 	}
 
 	app.render(w, r, http.StatusOK, "post-details.html", data)
@@ -189,7 +225,7 @@ func (app *application) handlePostsIdDeleteGet(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	p, err := app.models.Post.GetDetails(postID)
+	p, err := app.models.Post.GetDetails(r.Context(), postID)
 	if err != nil {
 		app.renderError(w, r, http.StatusBadRequest, "")
 
@@ -225,7 +261,7 @@ func (app *application) handlePostsIdDeletePost(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	userID, err := app.models.Post.GetUserID(postID)
+	userID, err := app.models.Post.GetUserID(r.Context(), postID)
 	if err != nil {
 		app.renderError(w, r, http.StatusBadRequest, "")
 
@@ -239,7 +275,7 @@ func (app *application) handlePostsIdDeletePost(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = app.models.Post.Delete(postID)
+	err = app.models.Post.Delete(r.Context(), postID)
 	if err != nil {
 		app.renderError(w, r, http.StatusBadRequest, "")
 
@@ -256,19 +292,27 @@ func (app *application) handlePostsIdDeletePost(w http.ResponseWriter, r *http.R
 }
 
 type newPostData struct {
-	Sports []*models.Sport
-	Skills []*models.SkillLevel
+	Sports  []*models.Sport
+	Skills  []*models.SkillLevel
+	Formats []*models.PostFormat
 }
 
 func (app *application) handlePostsNewGet(w http.ResponseWriter, r *http.Request) {
-	sports, err := app.models.Sport.All()
+	sports, err := app.models.Sport.All(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	skills, err := app.models.Skill.All(r.Context())
 	if err != nil {
 		app.serverError(w, r, err)
 
 		return
 	}
 
-	skills, err := app.models.Skill.All()
+	formats, err := app.models.PostFormat.All(r.Context())
 	if err != nil {
 		app.serverError(w, r, err)
 
@@ -276,17 +320,20 @@ func (app *application) handlePostsNewGet(w http.ResponseWriter, r *http.Request
 	}
 
 	data := newPostData{
-		Sports: sports,
-		Skills: skills,
+		Sports:  sports,
+		Skills:  skills,
+		Formats: formats,
 	}
 
 	app.render(w, r, http.StatusOK, "posts-new.html", data)
 }
 
 type newPostForm struct {
-	sport      int
-	skillLevel int
-	comment    string
+	sport         int
+	skillLevel    int
+	format        int
+	playersNeeded int
+	comment       string
 	validator.Validator
 }
 
@@ -313,7 +360,7 @@ func (app *application) handlePostsNewPost(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Check if user already has post with sport
-	postID, err := app.models.Post.GetID(suid, sportID)
+	postID, err := app.models.Post.GetID(r.Context(), suid, sportID)
 	if err != nil && !errors.Is(err, models.ErrNoRecord) {
 		app.serverError(w, r, err)
 
@@ -340,15 +387,48 @@ func (app *application) handlePostsNewPost(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	formatID, err := strconv.Atoi(r.Form.Get("format"))
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	format, err := app.models.PostFormat.Get(r.Context(), formatID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.renderError(w, r, http.StatusBadRequest, "")
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	// Singles posts only ever need one partner
+	playersNeeded := 1
+	if format.MaxPlayersNeeded > 1 {
+		playersNeeded, err = strconv.Atoi(r.Form.Get("players-needed"))
+		if err != nil {
+			app.renderError(w, r, http.StatusBadRequest, "")
+
+			return
+		}
+	}
+
 	form := newPostForm{
-		sport:      sportID,
-		skillLevel: skill,
-		comment:    r.Form.Get("comment"),
+		sport:         sportID,
+		skillLevel:    skill,
+		format:        format.ID,
+		playersNeeded: playersNeeded,
+		comment:       r.Form.Get("comment"),
 	}
 
 	form.Validate(validator.MaxChars(form.comment, 254), "invalid comment: must be no more than 254 characters long")
 	form.Validate(validator.PermittedInt(form.sport, 1, 2, 3, 4, 5, 6), "invalid sport")
 	form.Validate(validator.PermittedInt(form.skillLevel, 1, 2, 3, 4, 5), "invalid skill level")
+	form.Validate(form.playersNeeded >= 1, "invalid players needed: must be at least 1")
+	form.Validate(form.playersNeeded <= format.MaxPlayersNeeded, fmt.Sprintf("invalid players needed: must be no more than %d for %s", format.MaxPlayersNeeded, format.Name))
 
 	if !form.IsValid() {
 		validationError(w, form.Validator)
@@ -356,7 +436,7 @@ func (app *application) handlePostsNewPost(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	postID, err = app.models.Post.Insert(suid, form.sport, form.skillLevel, form.comment)
+	postID, err = app.models.Post.Insert(r.Context(), suid, form.sport, form.skillLevel, form.format, form.playersNeeded, form.comment)
 	if err != nil {
 		app.serverError(w, r, err)
 
@@ -367,3 +447,97 @@ func (app *application) handlePostsNewPost(w http.ResponseWriter, r *http.Reques
 
 	http.Redirect(w, r, url, http.StatusSeeOther)
 }
+
+func (app *application) handlePostsIdJoinPost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	userID, err := app.models.Post.GetUserID(r.Context(), postID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.renderError(w, r, http.StatusNotFound, "")
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil || suid == userID {
+		unauthorizedError(w)
+
+		return
+	}
+
+	url := fmt.Sprintf("/posts/%d", postID)
+
+	err = app.models.Roster.Insert(r.Context(), postID, suid)
+	if err != nil {
+		if errors.Is(err, models.ErrPostClosed) {
+			f := FlashMessage{
+				Type:    FlashError,
+				Message: "Unable to join: this post is already full.",
+			}
+			app.flash(r, f)
+
+			http.Redirect(w, r, url, http.StatusSeeOther)
+		} else if errors.Is(err, models.ErrDuplicateMember) {
+			http.Redirect(w, r, url, http.StatusSeeOther)
+		} else if errors.Is(err, models.ErrNoRecord) {
+			app.renderError(w, r, http.StatusNotFound, "")
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	f := FlashMessage{
+		Type:    FlashSuccess,
+		Message: "Successfully joined roster",
+	}
+	app.flash(r, f)
+
+	http.Redirect(w, r, url, http.StatusSeeOther)
+}
+
+func (app *application) handlePostsIdLeavePost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	err = app.models.Roster.Delete(r.Context(), postID, suid)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.renderError(w, r, http.StatusBadRequest, "")
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	f := FlashMessage{
+		Type:    FlashSuccess,
+		Message: "Successfully left roster",
+	}
+	app.flash(r, f)
+
+	url := fmt.Sprintf("/posts/%d", postID)
+	http.Redirect(w, r, url, http.StatusSeeOther)
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/micahco/racket-connections/internal/assert"
+)
+
+func TestPostsNewPost(t *testing.T) {
+	app, _ := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	userID := ts.newUser(t, app, testName, testEmail, testPassword)
+
+	tennisID, err := app.models.Post.Insert(context.Background(), userID, 1, 3, 1, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		sport         string
+		skillLevel    string
+		format        string
+		playersNeeded string
+		wantCode      int
+		wantLocation  string
+	}{
+		{
+			name:       "Singles",
+			sport:      "2",
+			skillLevel: "3",
+			format:     "1",
+			wantCode:   http.StatusSeeOther,
+		},
+		{
+			name:          "Doubles",
+			sport:         "3",
+			skillLevel:    "1",
+			format:        "2",
+			playersNeeded: "3",
+			wantCode:      http.StatusSeeOther,
+		},
+		{
+			name:         "Duplicate sport",
+			sport:        "1",
+			skillLevel:   "3",
+			format:       "1",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: fmt.Sprintf("/posts/%d", tennisID),
+		},
+		{
+			name:          "Too many players",
+			sport:         "4",
+			skillLevel:    "3",
+			format:        "2",
+			playersNeeded: "4",
+			wantCode:      http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "Missing players needed",
+			sport:      "4",
+			skillLevel: "3",
+			format:     "3",
+			wantCode:   http.StatusBadRequest,
+		},
+		{
+			name:       "Invalid sport",
+			sport:      "7",
+			skillLevel: "3",
+			format:     "1",
+			wantCode:   http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "Invalid skill level",
+			sport:      "4",
+			skillLevel: "6",
+			format:     "1",
+			wantCode:   http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "Invalid format",
+			sport:      "4",
+			skillLevel: "3",
+			format:     "4",
+			wantCode:   http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("sport", tt.sport)
+			form.Add("skill-level", tt.skillLevel)
+			form.Add("format", tt.format)
+			form.Add("players-needed", tt.playersNeeded)
+
+			code, header, _ := ts.postForm(t, "/posts/new", "/posts/new", form)
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantLocation != "" {
+				assert.Equal(t, header.Get("Location"), tt.wantLocation)
+			} else if code == http.StatusSeeOther {
+				assert.Equal(t, strings.HasPrefix(header.Get("Location"), "/posts/"), true)
+			}
+		})
+	}
+}
+
+func TestPostsIdDeletePost(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	owner := newTestServer(t, app.routes())
+	ownerID := owner.newUser(t, app, testName, testEmail, testPassword)
+
+	other := newTestServer(t, app.routes())
+	other.newUser(t, app, "Timmy Beaver", "timmy@oregonstate.edu", testPassword)
+
+	postID, err := app.models.Post.Insert(context.Background(), ownerID, 1, 3, 1, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deletePath := fmt.Sprintf("/posts/%d/delete", postID)
+
+	code, _, _ := other.get(t, deletePath)
+	assert.Equal(t, code, http.StatusUnauthorized)
+
+	code, _, _ = other.postForm(t, deletePath, "/posts", nil)
+	assert.Equal(t, code, http.StatusUnauthorized)
+
+	code, _, _ = owner.postForm(t, deletePath, deletePath, nil)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	_, err = app.models.Post.GetDetails(context.Background(), postID)
+	assert.Equal(t, err != nil, true)
+
+	code, _, _ = owner.postForm(t, deletePath, "/posts", nil)
+	assert.Equal(t, code, http.StatusBadRequest)
+}
+
+func TestPostsIdJoinPost(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	owner := newTestServer(t, app.routes())
+	ownerID := owner.newUser(t, app, testName, testEmail, testPassword)
+
+	first := newTestServer(t, app.routes())
+	first.newUser(t, app, "Timmy Beaver", "timmy@oregonstate.edu", testPassword)
+
+	second := newTestServer(t, app.routes())
+	second.newUser(t, app, "Tammy Beaver", "tammy@oregonstate.edu", testPassword)
+
+	postID, err := app.models.Post.Insert(context.Background(), ownerID, 1, 3, 1, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	postPath := fmt.Sprintf("/posts/%d", postID)
+
+	code, _, _ := owner.postForm(t, postPath+"/join", postPath+"/x", nil)
+	assert.Equal(t, code, http.StatusUnauthorized)
+
+	code, header, _ := first.postForm(t, postPath+"/join", postPath+"/x", nil)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), postPath)
+
+	// Singles posts close once a partner joins
+	p, err := app.models.Post.GetDetails(context.Background(), postID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, p.IsClosed(), true)
+
+	code, _, _ = second.postForm(t, postPath+"/join", postPath+"/x", nil)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	_, _, body := second.get(t, postPath+"/x")
+	assert.Equal(t, strings.Contains(body, "this post is already full"), true)
+
+	code, _, _ = first.postForm(t, postPath+"/leave", postPath+"/x", nil)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	p, err = app.models.Post.GetDetails(context.Background(), postID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, p.IsClosed(), false)
+
+	code, _, _ = second.postForm(t, postPath+"/leave", postPath+"/x", nil)
+	assert.Equal(t, code, http.StatusBadRequest)
+}
+
+func TestPostsIdJoinPostRoster(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	owner := newTestServer(t, app.routes())
+	ownerID := owner.newUser(t, app, testName, testEmail, testPassword)
+
+	code, _, _ := owner.postForm(t, "/posts/999/join", "/posts", nil)
+	assert.Equal(t, code, http.StatusNotFound)
+
+	tests := []struct {
+		name          string
+		format        int
+		playersNeeded int
+	}{
+		{
+			name:          "Singles",
+			format:        1,
+			playersNeeded: 1,
+		},
+		{
+			name:          "Doubles",
+			format:        2,
+			playersNeeded: 3,
+		},
+		{
+			name:          "Group",
+			format:        3,
+			playersNeeded: 2,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			postID, err := app.models.Post.Insert(context.Background(), ownerID, i+1, 3, tt.format, tt.playersNeeded, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			postPath := fmt.Sprintf("/posts/%d", postID)
+
+			isClosed := func() bool {
+				p, err := app.models.Post.GetDetails(context.Background(), postID)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return p.IsClosed()
+			}
+
+			// One more player than needed tries to join
+			players := make([]*testServer, tt.playersNeeded+1)
+			for j := range players {
+				players[j] = newTestServer(t, app.routes())
+				email := fmt.Sprintf("player%d.%d@oregonstate.edu", i, j)
+				players[j].newUser(t, app, "Timmy Beaver", email, testPassword)
+			}
+
+			for j, ts := range players[:tt.playersNeeded] {
+				code, _, _ := ts.postForm(t, postPath+"/join", postPath+"/x", nil)
+				assert.Equal(t, code, http.StatusSeeOther)
+				assert.Equal(t, isClosed(), j == tt.playersNeeded-1)
+			}
+
+			late := players[tt.playersNeeded]
+
+			code, _, _ := late.postForm(t, postPath+"/join", postPath+"/x", nil)
+			assert.Equal(t, code, http.StatusSeeOther)
+
+			members, err := app.models.Roster.Post(context.Background(), postID)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, len(members), tt.playersNeeded)
+
+			// Leaving a full post reopens it for the next player
+			code, _, _ = players[0].postForm(t, postPath+"/leave", postPath+"/x", nil)
+			assert.Equal(t, code, http.StatusSeeOther)
+			assert.Equal(t, isClosed(), false)
+
+			code, _, _ = late.postForm(t, postPath+"/join", postPath+"/x", nil)
+			assert.Equal(t, code, http.StatusSeeOther)
+			assert.Equal(t, isClosed(), true)
+		})
+	}
+}
+
+func TestPostsGet(t *testing.T) {
+	app, _ := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	code, header, _ := ts.get(t, "/posts")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/auth/login")
+
+	userID := ts.newUser(t, app, testName, testEmail, testPassword)
+
+	err := app.models.Timeslot.Insert(context.Background(), userID, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tennisID, err := app.models.Post.Insert(context.Background(), userID, 1, 3, 1, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	squashID, err := app.models.Post.Insert(context.Background(), userID, 6, 3, 1, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantTennis bool
+		wantSquash bool
+	}{
+		{
+			name:       "All",
+			query:      "",
+			wantTennis: true,
+			wantSquash: true,
+		},
+		{
+			name:       "Sport",
+			query:      "?sport=Tennis",
+			wantTennis: true,
+			wantSquash: false,
+		},
+		{
+			name:       "Sports",
+			query:      "?sport=tennis&sport=squash",
+			wantTennis: true,
+			wantSquash: true,
+		},
+		{
+			name:       "Available",
+			query:      "?mon-eve=on",
+			wantTennis: true,
+			wantSquash: true,
+		},
+		{
+			name:       "Unavailable",
+			query:      "?mon-mor=on",
+			wantTennis: false,
+			wantSquash: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, "/posts"+tt.query)
+			assert.Equal(t, code, http.StatusOK)
+			assert.Equal(t, strings.Contains(body, fmt.Sprintf(`href="/posts/%d/`, tennisID)), tt.wantTennis)
+			assert.Equal(t, strings.Contains(body, fmt.Sprintf(`href="/posts/%d/`, squashID)), tt.wantSquash)
+		})
+	}
+}
@@ -3,20 +3,25 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/micahco/racket-connections/internal/models"
 	"github.com/micahco/racket-connections/internal/validator"
 )
 
 type profileData struct {
-	Name      string
-	Email     string
-	Contacts  []*models.UserContact
-	Days      []*models.DayOfWeek
-	Times     []*models.TimeOfDay
-	Timeslots []*models.Timeslot
-	Posts     []*models.ProfilePost
+	Name       string
+	Email      string
+	Contacts   []*models.UserContact
+	Days       []*models.DayOfWeek
+	Times      []*models.TimeOfDay
+	Timeslots  []*models.Timeslot
+	Posts      []*models.ProfilePost
+	Matches    []*models.UserMatch
+	HeadToHead []*models.HeadToHead
+	Sessions   []*models.Session
 }
 
 func (app *application) handleProfileGet(w http.ResponseWriter, r *http.Request) {
@@ -27,54 +32,116 @@ func (app *application) handleProfileGet(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	u, err := app.models.User.GetProfile(suid)
+	u, err := app.models.User.GetProfile(r.Context(), suid)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	contacts, err := app.models.Contact.UserContacts(r.Context(), suid)
 	if err != nil {
 		app.serverError(w, r, err)
 
 		return
 	}
 
-	contacts, err := app.models.Contact.UserContacts(suid)
+	timeslots, err := app.models.Timeslot.User(r.Context(), suid)
 	if err != nil {
 		app.serverError(w, r, err)
 
 		return
 	}
 
-	timeslots, err := app.models.Timeslot.User(suid)
+	posts, err := app.models.Post.User(r.Context(), suid)
 	if err != nil {
 		app.serverError(w, r, err)
 
 		return
 	}
 
-	posts, err := app.models.Post.User(suid)
+	matches, err := app.models.Match.User(r.Context(), suid)
 	if err != nil {
 		app.serverError(w, r, err)
 
 		return
 	}
 
-	days, _ := app.models.Timeslot.Days()
-	times, _ := app.models.Timeslot.Times()
+	h2h, err := app.models.Match.HeadToHead(r.Context(), suid)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	sessions, err := app.models.Session.User(r.Context(), suid, app.sessionManager.Token(r.Context()))
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	days, _ := app.models.Timeslot.Days(r.Context())
+	times, _ := app.models.Timeslot.Times(r.Context())
 
 	data := profileData{
-		Name:      u.Name,
-		Email:     u.Email,
-		Contacts:  contacts,
-		Days:      days,
-		Times:     times,
-		Timeslots: timeslots,
-		Posts:     posts,
+		Name:       u.Name,
+		Email:      u.Email,
+		Contacts:   contacts,
+		Days:       days,
+		Times:      times,
+		Timeslots:  timeslots,
+		Posts:      posts,
+		Matches:    matches,
+		HeadToHead: h2h,
+		Sessions:   sessions,
 	}
 
 	app.render(w, r, http.StatusOK, "profile.html", data)
 }
 
+func (app *application) handleProfileSessionsRevokePost(w http.ResponseWriter, r *http.Request) {
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	err = app.models.Session.DeleteOthers(r.Context(), suid, app.sessionManager.Token(r.Context()))
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	f := FlashMessage{
+		Type:    FlashSuccess,
+		Message: "Signed out of all other devices.",
+	}
+	app.flash(r, f)
+
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+const (
+	// Accounts are kept for this long after the user asks to delete them
+	deletionGracePeriod = 14 * 24 * time.Hour
+	purgeDateLayout     = "January 2, 2006"
+)
+
+type deletionEmailData struct {
+	Name    string
+	PurgeAt string
+	Link    string
+}
+
 func (app *application) handleProfileDeleteGet(w http.ResponseWriter, r *http.Request) {
 	app.render(w, r, http.StatusOK, "profile-delete.html", nil)
 }
 
+// Schedules the account for deletion and signs the user out everywhere, so
+// that logging in again is what cancels it
 func (app *application) handleProfileDeletePost(w http.ResponseWriter, r *http.Request) {
 	suid, err := app.getSessionUserID(r)
 	if err != nil {
@@ -83,14 +150,48 @@ func (app *application) handleProfileDeletePost(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = app.models.User.Delete(suid)
+	err = app.models.Deletion.Request(r.Context(), suid, time.Now().Add(deletionGracePeriod))
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	d, err := app.models.Deletion.Get(r.Context(), suid)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	ref, err := url.Parse("/auth/login")
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	data := deletionEmailData{
+		Name:    d.Name,
+		PurgeAt: d.PurgeAt.Format(purgeDateLayout),
+		Link:    app.baseURL.ResolveReference(ref).String(),
+	}
+
+	err = app.enqueueEmail(r.Context(), d.Email, "account_deletion_requested.tmpl", data)
 	if err != nil {
 		app.serverError(w, r, err)
 
 		return
 	}
 
-	err = app.sessionManager.Clear(r.Context())
+	err = app.models.Session.DeleteOthers(r.Context(), suid, app.sessionManager.Token(r.Context()))
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	err = app.logout(r)
 	if err != nil {
 		app.serverError(w, r, err)
 
@@ -98,8 +199,8 @@ func (app *application) handleProfileDeletePost(w http.ResponseWriter, r *http.R
 	}
 
 	f := FlashMessage{
-		Type:    FlashSuccess,
-		Message: "Succesfully closed account",
+		Type:    FlashInfo,
+		Message: fmt.Sprintf("Your account will be deleted on %s. Log in before then to keep it.", data.PurgeAt),
 	}
 	app.flash(r, f)
 
@@ -119,14 +220,14 @@ func (app *application) handleProfileContactsGet(w http.ResponseWriter, r *http.
 		return
 	}
 
-	c, err := app.models.Contact.UserContacts(suid)
+	c, err := app.models.Contact.UserContacts(r.Context(), suid)
 	if err != nil {
 		app.serverError(w, r, err)
 
 		return
 	}
 
-	m, _ := app.models.Contact.Methods()
+	m, _ := app.models.Contact.Methods(r.Context())
 
 	data := profileContactsData{
 		Contacts: c,
@@ -182,7 +283,7 @@ func (app *application) handleProfileContactsPost(w http.ResponseWriter, r *http
 		return
 	}
 
-	userContacts, err := app.models.Contact.UserContacts(suid)
+	userContacts, err := app.models.Contact.UserContacts(r.Context(), suid)
 	if err != nil {
 		app.serverError(w, r, err)
 
@@ -203,12 +304,12 @@ func (app *application) handleProfileContactsPost(w http.ResponseWriter, r *http
 		}
 	}
 
-	methodID, err := app.models.Contact.MethodID(form.contactMethod)
+	methodID, err := app.models.Contact.MethodID(r.Context(), form.contactMethod)
 	if err != nil {
 		app.serverError(w, r, err)
 	}
 
-	app.models.Contact.Insert(form.contactValue, suid, methodID)
+	app.models.Contact.Insert(r.Context(), form.contactValue, suid, methodID)
 
 	http.Redirect(w, r, "/profile/contacts", http.StatusSeeOther)
 }
@@ -221,7 +322,7 @@ func (app *application) handleProfileContactsDeletePost(w http.ResponseWriter, r
 		return
 	}
 
-	c, err := app.models.Contact.UserContacts(suid)
+	c, err := app.models.Contact.UserContacts(r.Context(), suid)
 	if err != nil {
 		app.serverError(w, r, err)
 
@@ -254,7 +355,7 @@ func (app *application) handleProfileContactsDeletePost(w http.ResponseWriter, r
 		return
 	}
 
-	err = app.models.Contact.Delete(id)
+	err = app.models.Contact.Delete(r.Context(), id)
 	if err != nil {
 		app.renderError(w, r, http.StatusBadRequest, "")
 
@@ -278,15 +379,15 @@ func (app *application) handleProfileAvailabilityGet(w http.ResponseWriter, r *h
 		return
 	}
 
-	timeslots, err := app.models.Timeslot.User(suid)
+	timeslots, err := app.models.Timeslot.User(r.Context(), suid)
 	if err != nil {
 		app.serverError(w, r, err)
 
 		return
 	}
 
-	days, _ := app.models.Timeslot.Days()
-	times, _ := app.models.Timeslot.Times()
+	days, _ := app.models.Timeslot.Days(r.Context())
+	times, _ := app.models.Timeslot.Times(r.Context())
 
 	data := profileAvailabilityData{
 		Days:      days,
@@ -298,7 +399,6 @@ func (app *application) handleProfileAvailabilityGet(w http.ResponseWriter, r *h
 }
 
 func (app *application) handleProfileAvailabilityPost(w http.ResponseWriter, r *http.Request) {
-
 	err := r.ParseForm()
 	if err != nil {
 		app.renderError(w, r, http.StatusBadRequest, "")
@@ -313,26 +413,48 @@ func (app *application) handleProfileAvailabilityPost(w http.ResponseWriter, r *
 		return
 	}
 
-	err = app.models.Timeslot.DeleteUser(suid)
+	// Replace the user's availability in one transaction so a failure
+	// doesn't leave them with none
+	err = app.models.Tx.Do(r.Context(), func(tx models.Models) error {
+		err := tx.Timeslot.DeleteUser(r.Context(), suid)
+		if err != nil {
+			return err
+		}
+
+		return insertTimeslots(r, tx, suid)
+	})
 	if err != nil {
 		app.serverError(w, r, err)
 
 		return
 	}
 
-	days, _ := app.models.Timeslot.Days()
-	times, _ := app.models.Timeslot.Times()
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+// Inserts a timeslot for each day-time checkbox checked in the parsed form
+func insertTimeslots(r *http.Request, m models.Models, userID int) error {
+	days, err := m.Timeslot.Days(r.Context())
+	if err != nil {
+		return err
+	}
+
+	times, err := m.Timeslot.Times(r.Context())
+	if err != nil {
+		return err
+	}
+
 	for _, d := range days {
 		for _, t := range times {
 			key := fmt.Sprintf("%s-%s", d.Abbrev, t.Abbrev)
 			if r.Form.Get(key) == "on" {
-				err = app.models.Timeslot.Insert(suid, d.ID, t.ID)
+				err = m.Timeslot.Insert(r.Context(), userID, d.ID, t.ID)
 				if err != nil {
-					app.serverError(w, r, err)
+					return err
 				}
 			}
 		}
 	}
 
-	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+	return nil
 }
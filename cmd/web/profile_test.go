@@ -0,0 +1,405 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/assert"
+	"github.com/micahco/racket-connections/internal/models"
+	"github.com/micahco/racket-connections/internal/models/fakes"
+)
+
+func TestProfileContactsPost(t *testing.T) {
+	app, _ := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	userID := ts.newUser(t, app, testName, testEmail, testPassword)
+
+	tests := []struct {
+		name      string
+		method    string
+		value     string
+		wantCode  int
+		wantCount int
+		wantFlash string
+	}{
+		{
+			name:      "Phone",
+			method:    "phone",
+			value:     "541-737-1000",
+			wantCode:  http.StatusSeeOther,
+			wantCount: 1,
+		},
+		{
+			name:      "Email",
+			method:    "email",
+			value:     "benny@example.com",
+			wantCode:  http.StatusSeeOther,
+			wantCount: 2,
+		},
+		{
+			name:      "Duplicate",
+			method:    "phone",
+			value:     "541-737-1000",
+			wantCode:  http.StatusSeeOther,
+			wantCount: 2,
+			wantFlash: "duplicate value",
+		},
+		{
+			name:      "Invalid phone",
+			method:    "phone",
+			value:     "five",
+			wantCode:  http.StatusUnprocessableEntity,
+			wantCount: 2,
+		},
+		{
+			name:      "Unknown method",
+			method:    "carrier pigeon",
+			value:     "Coo",
+			wantCode:  http.StatusBadRequest,
+			wantCount: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("contact-method", tt.method)
+			form.Add("contact-value", tt.value)
+
+			code, _, _ := ts.postForm(t, "/profile/contacts", "/profile/contacts", form)
+			assert.Equal(t, code, tt.wantCode)
+
+			contacts, err := app.models.Contact.UserContacts(context.Background(), userID)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, len(contacts), tt.wantCount)
+
+			if tt.wantFlash != "" {
+				_, _, body := ts.get(t, "/profile/contacts")
+				assert.Equal(t, strings.Contains(body, tt.wantFlash), true)
+			}
+		})
+	}
+}
+
+func TestProfileContactsDeletePost(t *testing.T) {
+	app, _ := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	userID := ts.newUser(t, app, testName, testEmail, testPassword)
+
+	err := app.models.Contact.Insert(context.Background(), "benny@example.com", userID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.models.Contact.Insert(context.Background(), "541-737-1000", userID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contacts, err := app.models.Contact.UserContacts(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{}
+	form.Add("id", strconv.Itoa(contacts[0].ID))
+
+	code, _, _ := ts.postForm(t, "/profile/contacts/delete", "/profile/contacts", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	// The last contact method cannot be deleted
+	form = url.Values{}
+	form.Add("id", strconv.Itoa(contacts[1].ID))
+
+	code, _, _ = ts.postForm(t, "/profile/contacts/delete", "/profile/contacts", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	_, _, body := ts.get(t, "/profile/contacts")
+	assert.Equal(t, strings.Contains(body, "minimum one required"), true)
+
+	contacts, err = app.models.Contact.UserContacts(context.Background(), userID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(contacts), 1)
+}
+
+func TestProfileAvailabilityPost(t *testing.T) {
+	app, _ := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	userID := ts.newUser(t, app, testName, testEmail, testPassword)
+
+	err := app.models.Timeslot.Insert(context.Background(), userID, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{}
+	form.Add("tue-aft", "on")
+	form.Add("sun-eve", "on")
+
+	code, header, _ := ts.postForm(t, "/profile/availability", "/profile/availability", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/profile")
+
+	timeslots, err := app.models.Timeslot.User(context.Background(), userID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(timeslots), 2)
+	assert.Equal(t, timeslots[0].Day.Abbrev+"-"+timeslots[0].Time.Abbrev, "tue-aft")
+	assert.Equal(t, timeslots[1].Day.Abbrev+"-"+timeslots[1].Time.Abbrev, "sun-eve")
+}
+
+func TestProfileDeletePost(t *testing.T) {
+	app, mt := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	ctx := context.Background()
+
+	userID := ts.newUser(t, app, testName, testEmail, testPassword)
+
+	err := app.models.Timeslot.Insert(ctx, userID, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lastSubject := func() string {
+		app.sendDueEmails()
+
+		msg := mt.Last(testEmail)
+		if msg == nil {
+			return ""
+		}
+
+		return msg.Subject
+	}
+
+	// Requesting deletion logs the user out and keeps the account
+	code, header, _ := ts.postForm(t, "/profile/delete", "/profile/delete", nil)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/")
+	assert.Equal(t, lastSubject(), "Account deletion scheduled")
+
+	code, header, _ = ts.get(t, "/profile")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/auth/login")
+
+	d, err := app.models.Deletion.Get(ctx, userID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, d.PurgeAt.Sub(d.RequestedAt).Round(time.Hour), deletionGracePeriod)
+
+	app.purgeDueAccounts()
+
+	exists, err := app.models.User.Exists(ctx, userID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, exists, true)
+
+	// Logging in during the grace period cancels the deletion
+	ts.login(t, testEmail, testPassword)
+	assert.Equal(t, lastSubject(), "Account deletion cancelled")
+
+	_, err = app.models.Deletion.Get(ctx, userID)
+	assert.Equal(t, err, models.ErrNoRecord)
+
+	// Once the grace period has passed the account is purged
+	code, _, _ = ts.postForm(t, "/profile/delete", "/profile/delete", nil)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	fakes.ExpireDeletion(app.models, userID)
+	app.purgeDueAccounts()
+
+	exists, err = app.models.User.Exists(ctx, userID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, exists, false)
+
+	timeslots, err := app.models.Timeslot.User(ctx, userID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(timeslots), 0)
+
+	assert.Equal(t, lastSubject(), "Account deleted")
+}
+
+func TestProfileDeletePostKeepsOpponentMatches(t *testing.T) {
+	app, _ := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	ctx := context.Background()
+
+	userID := ts.newUser(t, app, testName, testEmail, testPassword)
+
+	opponentID, err := app.models.User.Insert(ctx, "Opponent", "opponent@oregonstate.edu", testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sets := []models.MatchSet{{PlayerScore: 6, OpponentScore: 4}}
+	playedOn := time.Now().Truncate(24 * time.Hour)
+
+	confirmedID, err := app.models.Match.Insert(ctx, 1, userID, opponentID, userID, playedOn, sets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.models.Match.Confirm(ctx, confirmedID, opponentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Left unconfirmed, so it goes with the account
+	_, err = app.models.Match.Insert(ctx, 1, opponentID, userID, opponentID, playedOn, sets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, _, _ := ts.postForm(t, "/profile/delete", "/profile/delete", nil)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	fakes.ExpireDeletion(app.models, userID)
+	app.purgeDueAccounts()
+
+	exists, err := app.models.User.Exists(ctx, userID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, exists, false)
+
+	matches, err := app.models.Match.User(ctx, opponentID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(matches), 1)
+	assert.Equal(t, matches[0].ID, confirmedID)
+	assert.Equal(t, matches[0].OpponentName, "Deleted user")
+	assert.Equal(t, matches[0].Score, "4-6")
+	assert.Equal(t, matches[0].Won, false)
+
+	h2h, err := app.models.Match.HeadToHead(ctx, opponentID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(h2h), 1)
+	assert.Equal(t, h2h[0].Wins, 0)
+	assert.Equal(t, h2h[0].Losses, 1)
+}
+
+func TestProfileExportPost(t *testing.T) {
+	app, mt := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	userID := ts.newUser(t, app, testName, testEmail, testPassword)
+
+	err := app.models.Contact.Insert(context.Background(), "541-737-1000", userID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.models.Timeslot.Insert(context.Background(), userID, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A spreadsheet would evaluate this comment unless it is escaped
+	_, err = app.models.Post.Insert(context.Background(), userID, 1, 3, 2, 3, "=1+1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posterID, err := app.models.User.Insert(context.Background(), "Poster", "poster@oregonstate.edu", testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	postID, err := app.models.Post.Insert(context.Background(), posterID, 2, 3, 1, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.models.Roster.Insert(context.Background(), postID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.models.Identity.Insert(context.Background(), "https://accounts.google.com", "subject-1", userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, header, _ := ts.postForm(t, "/profile/export", "/profile", nil)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/profile")
+
+	link := emailedLink(t, app, mt, testEmail)
+	assert.Equal(t, strings.HasPrefix(link, "/profile/export/download?token="), true)
+
+	// Only one export may be requested per day
+	code, header, _ = ts.postForm(t, "/profile/export", "/profile", nil)
+	assert.Equal(t, code, http.StatusTooManyRequests)
+	assert.Equal(t, header.Get("Retry-After") != "", true)
+
+	code, _, _ = ts.get(t, "/profile/export/download?token=wrong")
+	assert.Equal(t, code, http.StatusUnauthorized)
+
+	// The link requires the account to be logged in
+	other := newTestServer(t, app.routes())
+
+	code, header, _ = other.get(t, link)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/auth/login")
+
+	code, header, body := ts.get(t, link)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, header.Get("Content-Type"), "application/zip")
+
+	zr, err := zip.NewReader(strings.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files[f.Name] = string(b)
+	}
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{file: "profile.csv", want: testEmail},
+		{file: "contacts.csv", want: "phone,541-737-1000"},
+		{file: "availability.csv", want: "day,time"},
+		{file: "posts.csv", want: "tennis"},
+		{file: "posts.csv", want: "doubles,3,'=1+1"},
+		{file: "rosters.csv", want: "badminton,Poster"},
+		{file: "identities.csv", want: "https://accounts.google.com,subject-1"},
+		{file: "sessions.csv", want: "true"},
+		{file: "data.json", want: `"email": "` + testEmail + `"`},
+		{file: "data.json", want: `"comment": "=1+1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			assert.Equal(t, strings.Contains(files[tt.file], tt.want), true)
+		})
+	}
+
+	var data map[string][]map[string]string
+	err = json.Unmarshal([]byte(files["data.json"]), &data)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(data["availability"]), 1)
+	assert.Equal(t, len(data["posts"]), 1)
+	assert.Equal(t, len(data["rosters"]), 1)
+	assert.Equal(t, len(data["identities"]), 1)
+}
@@ -9,17 +9,20 @@ import (
 
 func (app *application) routes() http.Handler {
 	r := chi.NewRouter()
+	r.Use(requestIDs)
+	r.Use(app.logRequests)
+	r.Use(app.instrument)
 	r.Use(app.recovery)
 	r.Use(secureHeaders)
 
-	if app.isDevelopment {
-		r.Use(app.logRequests)
-	}
-
 	r.NotFound(app.handleNotFound)
 	r.Handle("/static/*", app.handleStatic())
 	r.Get("/favicon.ico", app.handleFavicon)
 
+	if app.metricsToken != "" {
+		r.Handle("/metrics", requireMetricsToken(app.metricsToken, app.handleMetrics()))
+	}
+
 	r.Route("/", func(r chi.Router) {
 		r.Use(app.sessionManager.LoadAndSave)
 		r.Use(app.noSurf)
@@ -42,6 +45,15 @@ func (app *application) routes() http.Handler {
 			r.Post("/reset", app.handleAuthResetPost)
 			r.Get("/reset/update", app.handleAuthResetUpdateGet)
 			r.Post("/reset/update", app.handleAuthResetUpdatePost)
+			r.Get("/link", app.handleAuthLinkGet)
+			r.Post("/link", app.handleAuthLinkPost)
+			r.Post("/link/login", app.handleAuthLinkLoginPost)
+			r.Get("/oidc", app.handleAuthOIDCGet)
+			r.Get("/oidc/callback", app.handleAuthOIDCCallbackGet)
+			r.Get("/oidc/register", app.handleAuthOIDCRegisterGet)
+			r.Post("/oidc/register", app.handleAuthOIDCRegisterPost)
+			r.Get("/2fa", app.handleAuthTwoFactorGet)
+			r.Post("/2fa", app.handleAuthTwoFactorPost)
 			r.NotFound(app.handleNotFound)
 		})
 
@@ -54,11 +66,77 @@ func (app *application) routes() http.Handler {
 			r.Post("/contacts/delete", app.handleProfileContactsDeletePost)
 			r.Get("/availability", app.handleProfileAvailabilityGet)
 			r.Post("/availability", app.handleProfileAvailabilityPost)
+			r.Post("/sessions/revoke", app.handleProfileSessionsRevokePost)
+			r.Post("/export", app.handleProfileExportPost)
+			r.Get("/export/download", app.handleProfileExportDownloadGet)
+			r.Get("/2fa", app.handleProfileTwoFactorGet)
+			r.Get("/2fa/qr", app.handleProfileTwoFactorQRGet)
+			r.Post("/2fa/enable", app.handleProfileTwoFactorEnablePost)
+			r.Post("/2fa/disable", app.handleProfileTwoFactorDisablePost)
 			r.Get("/delete", app.handleProfileDeleteGet)
 			r.Post("/delete", app.handleProfileDeletePost)
 			r.NotFound(app.handleNotFound)
 		})
 
+		r.Route("/ladders", func(r chi.Router) {
+			r.Use(app.requireAuthentication)
+
+			r.Get("/", app.handleLaddersGet)
+			r.NotFound(app.handleNotFound)
+
+			r.Route("/{sportID}", func(r chi.Router) {
+				r.Get("/", app.handleLadderGet)
+				r.Post("/join", app.handleLadderJoinPost)
+				r.Post("/leave", app.handleLadderLeavePost)
+				r.Post("/challenge", app.handleLadderChallengePost)
+				r.Post("/challenges/{challengeID}/report", app.handleLadderChallengeReportPost)
+				r.Post("/challenges/{challengeID}/confirm", app.handleLadderChallengeConfirmPost)
+				r.Post("/challenges/{challengeID}/reject", app.handleLadderChallengeRejectPost)
+				r.NotFound(app.handleNotFound)
+			})
+		})
+
+		r.Route("/matches", func(r chi.Router) {
+			r.Use(app.requireAuthentication)
+
+			r.Get("/new", app.handleMatchesNewGet)
+			r.Post("/new", app.handleMatchesNewPost)
+			r.Post("/{id}/confirm", app.handleMatchesIdConfirmPost)
+			r.Post("/{id}/reject", app.handleMatchesIdRejectPost)
+			r.NotFound(app.handleNotFound)
+		})
+
+		r.Route("/tournaments", func(r chi.Router) {
+			r.Use(app.requireAuthentication)
+
+			r.Get("/", app.handleTournamentsGet)
+			r.Get("/new", app.handleTournamentsNewGet)
+			r.Post("/new", app.handleTournamentsNewPost)
+			r.NotFound(app.handleNotFound)
+
+			r.Route("/{id}", func(r chi.Router) {
+				r.Get("/", app.handleTournamentsIdGet)
+				r.Post("/register", app.handleTournamentsIdRegisterPost)
+				r.Post("/withdraw", app.handleTournamentsIdWithdrawPost)
+				r.Post("/start", app.handleTournamentsIdStartPost)
+				r.Post("/matches/{matchID}/result", app.handleTournamentsIdResultPost)
+				r.NotFound(app.handleNotFound)
+			})
+		})
+
+		if app.isDevelopment {
+			r.Get("/dev/emails", app.handleDevEmailsGet)
+			r.Get("/dev/emails/{name}", app.handleDevEmailsNameGet)
+		}
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(app.requireAuthentication)
+			r.Use(app.requireAdmin)
+
+			r.Get("/emails", app.handleAdminEmailsGet)
+			r.NotFound(app.handleNotFound)
+		})
+
 		r.Route("/posts", func(r chi.Router) {
 			r.Use(app.requireAuthentication)
 
@@ -73,6 +151,8 @@ func (app *application) routes() http.Handler {
 				r.Get("/*", app.handlePostsIdGet)
 				r.Get("/delete", app.handlePostsIdDeleteGet)
 				r.Post("/delete", app.handlePostsIdDeletePost)
+				r.Post("/join", app.handlePostsIdJoinPost)
+				r.Post("/leave", app.handlePostsIdLeavePost)
 				r.NotFound(app.handleNotFound)
 			})
 		})
@@ -108,6 +188,10 @@ func (app *application) handleFavicon(w http.ResponseWriter, r *http.Request) {
 	http.ServeFileFS(w, r, ui.Files, "static/favicon.ico")
 }
 
+type loginData struct {
+	HasSSO bool
+}
+
 func (app *application) handleRoot(w http.ResponseWriter, r *http.Request) {
 	if app.isAuthenticated(r) {
 		http.Redirect(w, r, "/posts", http.StatusSeeOther)
@@ -115,7 +199,11 @@ func (app *application) handleRoot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	app.render(w, r, http.StatusOK, "login.html", nil)
+	data := loginData{
+		HasSSO: app.oidc != nil,
+	}
+
+	app.render(w, r, http.StatusOK, "login.html", data)
 }
 
 func (app *application) handleRedirectToRoot(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const shutdownTimeout = 30 * time.Second
+
+// Serves until SIGINT or SIGTERM, then stops accepting requests, waits for
+// in-flight requests and background jobs to finish and returns.
+func (app *application) serve(srv *http.Server) error {
+	shutdownErr := make(chan error)
+
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+
+		app.logger.Info("shutting down", "signal", s.String())
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		// Drain even if in-flight requests were cut off, so that workers are
+		// stopped and jobs get what time is left before the pool closes
+		err := srv.Shutdown(ctx)
+		shutdownErr <- errors.Join(err, app.drain(ctx))
+	}()
+
+	err := srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	err = <-shutdownErr
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("stopped server")
+
+	return nil
+}
+
+// Signals long running jobs to stop and waits for background jobs to finish
+func (app *application) drain(ctx context.Context) error {
+	close(app.shutdown)
+
+	jobs, workers := app.pendingJobs.Load(), app.workers.Load()
+	app.logger.Info("draining background jobs", "jobs", jobs, "workers", workers)
+
+	done := make(chan struct{})
+	go func() {
+		app.jobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		app.logger.Info("drained background jobs", "jobs", jobs, "workers", workers)
+
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("timed out draining background jobs: %d jobs and %d workers remaining",
+			app.pendingJobs.Load(), app.workers.Load())
+	}
+}
+
+// Serves metrics on a separate listen address until shutdown
+func (app *application) serveMetrics(addr string) {
+	srv := &http.Server{
+		Addr:     addr,
+		ErrorLog: slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+		Handler:  app.handleMetrics(),
+	}
+
+	app.worker(func() {
+		go func() {
+			<-app.shutdown
+			srv.Close()
+		}()
+
+		app.logger.Info("serving metrics", "addr", addr)
+
+		err := srv.ListenAndServe()
+		if !errors.Is(err, http.ErrServerClosed) {
+			app.logger.Error(err.Error())
+		}
+	})
+}
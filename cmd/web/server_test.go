@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/assert"
+)
+
+func TestDrain(t *testing.T) {
+	logger, err := newLogger(io.Discard, "text", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &application{
+		logger:   logger,
+		shutdown: make(chan struct{}),
+	}
+
+	sent := false
+	app.background(func() {
+		time.Sleep(10 * time.Millisecond)
+		sent = true
+	})
+
+	stopped := false
+	app.worker(func() {
+		<-app.shutdown
+		stopped = true
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = app.drain(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, sent, true)
+	assert.Equal(t, stopped, true)
+	assert.Equal(t, app.pendingJobs.Load(), 0)
+	assert.Equal(t, app.workers.Load(), 0)
+}
+
+func TestDrainTimeout(t *testing.T) {
+	logger, err := newLogger(io.Discard, "text", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &application{
+		logger:   logger,
+		shutdown: make(chan struct{}),
+	}
+
+	block := make(chan struct{})
+	defer close(block)
+
+	app.background(func() {
+		<-block
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = app.drain(ctx)
+	assert.Equal(t, err != nil, true)
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/justinas/nosurf"
+	"github.com/micahco/racket-connections/internal/models"
 	"github.com/micahco/racket-connections/ui"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
@@ -137,6 +138,57 @@ func stripPhone(input string) string {
 	return result.String()
 }
 
+// Reports whether timeslots contains a slot on the given day and time
+func hasTimeslot(timeslots []*models.Timeslot, dayID, timeID int) bool {
+	for _, t := range timeslots {
+		if t.Day.ID == dayID && t.Time.ID == timeID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Summarizes a user agent as a browser and operating system, such as
+// "Firefox on Windows"
+func deviceName(userAgent string) string {
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	} {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	os := ""
+	for _, o := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, o.token) {
+			os = o.name
+			break
+		}
+	}
+
+	if os == "" {
+		return browser
+	}
+
+	return browser + " on " + os
+}
+
 var functions = template.FuncMap{
 	"sinceDate":    sinceDate,
 	"humanDate":    humanDate,
@@ -144,6 +196,8 @@ var functions = template.FuncMap{
 	"capitalize":   capitalize,
 	"stripPhone":   stripPhone,
 	"queryEscape":  url.QueryEscape,
+	"hasTimeslot":  hasTimeslot,
+	"deviceName":   deviceName,
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
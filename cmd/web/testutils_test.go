@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/pgxstore"
+	"github.com/alexedwards/scs/v2"
+	"github.com/alexedwards/scs/v2/memstore"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/micahco/racket-connections/internal/crypto"
+	"github.com/micahco/racket-connections/internal/mailer"
+	"github.com/micahco/racket-connections/internal/models"
+	"github.com/micahco/racket-connections/internal/models/fakes"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	testName     = "Benny Beaver"
+	testEmail    = "benny@oregonstate.edu"
+	testPassword = "Violet-Kettle-Harbor-58"
+)
+
+// Connects to a disposable schema with sql/init.sql applied. The schema is
+// dropped when the test finishes. Skips the test unless RC_TEST_DB_URL is set.
+func newTestDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	connString := os.Getenv("RC_TEST_DB_URL")
+	if connString == "" {
+		t.Skip("RC_TEST_DB_URL not set")
+	}
+
+	ctx := context.Background()
+
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := crypto.GenerateRandomBytes(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := fmt.Sprintf("test_%x", b)
+
+	_, err = conn.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS citext; CREATE SCHEMA "+schema+";")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Extensions live in the public schema
+	config.ConnConfig.RuntimeParams["search_path"] = schema + ",public"
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		pool.Close()
+
+		_, err := conn.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE;")
+		if err != nil {
+			t.Error(err)
+		}
+
+		conn.Close(ctx)
+	})
+
+	script, err := os.ReadFile("../../sql/init.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pool.Exec(ctx, string(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pool
+}
+
+// Returns an application backed by in-memory fakes. Emails are kept in the
+// returned transport once delivered with app.sendDueEmails.
+func newTestApplication(t *testing.T) (*application, *mailer.MemoryTransport) {
+	t.Helper()
+
+	logger, err := newLogger(io.Discard, "text", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tc, err := newTemplateCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gob.Register(FlashMessage{})
+
+	sm := scs.New()
+	sm.Store = memstore.NewWithCleanupInterval(0)
+	sm.Lifetime = 12 * time.Hour
+	sm.Cookie.Secure = true
+
+	mt := mailer.NewMemoryTransport()
+
+	app := &application{
+		logger:         logger,
+		baseURL:        &url.URL{Scheme: "https", Host: "racket.example.com"},
+		models:         fakes.New(),
+		templateCache:  tc,
+		sessionManager: sm,
+		mailer:         mailer.New(mt, &mail.Address{Address: "no-reply@example.com"}),
+		shutdown:       make(chan struct{}),
+		outbox:         make(chan struct{}, 1),
+	}
+	app.metrics = newMetrics(nil, app.models)
+
+	return app, mt
+}
+
+// Returns an application backed by a test database
+func newTestDBApplication(t *testing.T) (*application, *mailer.MemoryTransport) {
+	t.Helper()
+
+	pool := newTestDB(t)
+
+	app, mt := newTestApplication(t)
+	app.models = models.New(pool, &crypto.BcryptHasher{Cost: bcrypt.MinCost}, defaultQueryTimeout)
+	app.sessionManager.Store = pgxstore.NewWithCleanupInterval(pool, 0)
+	app.metrics = newMetrics(pool, app.models)
+
+	return app, mt
+}
+
+type testServer struct {
+	*httptest.Server
+}
+
+// Starts a TLS server, so that secure cookies are kept, with a client that
+// stores cookies and does not follow redirects
+func newTestServer(t *testing.T, h http.Handler) *testServer {
+	t.Helper()
+
+	ts := httptest.NewTLSServer(h)
+	t.Cleanup(ts.Close)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts.Client().Jar = jar
+	ts.Client().CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &testServer{ts}
+}
+
+func (ts *testServer) do(t *testing.T, req *http.Request) (int, http.Header, string) {
+	t.Helper()
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, string(body)
+}
+
+func (ts *testServer) get(t *testing.T, urlPath string) (int, http.Header, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+urlPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ts.do(t, req)
+}
+
+// Posts the form with the CSRF token from the page at csrfPath
+func (ts *testServer) postForm(t *testing.T, urlPath, csrfPath string, form url.Values) (int, http.Header, string) {
+	t.Helper()
+
+	if form == nil {
+		form = url.Values{}
+	}
+
+	form.Set("csrf_token", ts.csrfToken(t, csrfPath))
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+urlPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Referer", ts.URL+csrfPath)
+
+	return ts.do(t, req)
+}
+
+var csrfTokenRX = regexp.MustCompile(`<input type="hidden" name="csrf_token" value="(.+?)">`)
+
+// Returns the CSRF token from a form on the page
+func (ts *testServer) csrfToken(t *testing.T, urlPath string) string {
+	t.Helper()
+
+	_, _, body := ts.get(t, urlPath)
+
+	matches := csrfTokenRX.FindStringSubmatch(body)
+	if len(matches) < 2 {
+		t.Fatalf("no csrf token found on %s", urlPath)
+	}
+
+	return html.UnescapeString(matches[1])
+}
+
+// Logs in through the login form and fails the test unless it succeeds
+func (ts *testServer) login(t *testing.T, email, password string) {
+	t.Helper()
+
+	form := url.Values{}
+	form.Add("email", email)
+	form.Add("password", password)
+
+	code, header, body := ts.postForm(t, "/auth/login", "/", form)
+	if code != http.StatusSeeOther || header.Get("Location") != "/" {
+		t.Fatalf("login failed: %d %s", code, body)
+	}
+}
+
+// Creates a user with the given credentials and logs them in
+func (ts *testServer) newUser(t *testing.T, app *application, name, email, password string) int {
+	t.Helper()
+
+	id, err := app.models.User.Insert(context.Background(), name, email, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts.login(t, email, password)
+
+	return id
+}
+
+var linkRX = regexp.MustCompile(`https?://\S+`)
+
+// Delivers queued emails and returns the path and query of the first link in
+// the latest email to the recipient
+func emailedLink(t *testing.T, app *application, mt *mailer.MemoryTransport, recipient string) string {
+	t.Helper()
+
+	app.sendDueEmails()
+
+	msg := mt.Last(recipient)
+	if msg == nil {
+		t.Fatalf("no email sent to %s", recipient)
+	}
+
+	link := linkRX.FindString(msg.Body)
+	if link == "" {
+		t.Fatalf("no link in email: %s", msg.Body)
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u.RequestURI()
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+// Sliding window limits on attempts at an authentication action
+type rateLimit struct {
+	perIP    int
+	perEmail int
+	window   time.Duration
+}
+
+var rateLimits = map[string]rateLimit{
+	"login":  {perIP: 30, perEmail: 10, window: 15 * time.Minute},
+	"signup": {perIP: 10, perEmail: 3, window: time.Hour},
+	"reset":  {perIP: 10, perEmail: 3, window: time.Hour},
+	"link":   {perIP: 10, perEmail: 3, window: time.Hour},
+	"2fa":    {perIP: 30, perEmail: 5, window: 15 * time.Minute},
+}
+
+const (
+	// Consecutive failed logins that lock the account
+	lockoutThreshold = 5
+	lockoutDuration  = 15 * time.Minute
+	maxLoginDelay    = 4 * time.Second
+)
+
+// Returns the client's address. Behind a proxy every request comes from the
+// proxy, so the address is read from the header it sets when one is
+// configured. The header is only trusted if the proxy always overwrites it.
+func (app *application) clientIP(r *http.Request) string {
+	if app.clientIPHeader != "" {
+		ip := net.ParseIP(strings.TrimSpace(r.Header.Get(app.clientIPHeader)))
+		if ip != nil {
+			return ip.String()
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// Returns how long to wait before responding to a failed login. The first two
+// failures are not delayed, after which the delay doubles up to a maximum.
+func loginDelay(failures int) time.Duration {
+	if failures < 3 {
+		return 0
+	}
+
+	d := time.Second << (failures - 3)
+	if d > maxLoginDelay || d <= 0 {
+		return maxLoginDelay
+	}
+
+	return d
+}
+
+// Checks the rate limits for the action and records the attempt. Responds
+// with 429 Too Many Requests and returns false if either limit is exceeded.
+func (app *application) allowAttempt(w http.ResponseWriter, r *http.Request, action, email string) bool {
+	limit := rateLimits[action]
+	ip := app.clientIP(r)
+
+	byIP, byEmail, err := app.models.AuthAttempt.Count(r.Context(), action, ip, email, time.Now().Add(-limit.window))
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return false
+	}
+
+	if byIP >= limit.perIP || byEmail >= limit.perEmail {
+		app.tooManyRequests(w, r, limit.window, "Too many attempts. Please try again later.")
+
+		return false
+	}
+
+	// Failed logins are recorded once the credentials have been checked
+	if action != "login" {
+		err = app.models.AuthAttempt.Insert(r.Context(), action, ip, email, false)
+		if err != nil {
+			app.serverError(w, r, err)
+
+			return false
+		}
+	}
+
+	return true
+}
+
+// Responds with 429 Too Many Requests if logins for the email are locked
+// out and returns the most recent lockout, if any.
+func (app *application) checkLockout(w http.ResponseWriter, r *http.Request, email string) (*models.Lockout, bool) {
+	l, err := app.models.Lockout.Get(r.Context(), email)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+
+		return nil, false
+	}
+
+	if l != nil && l.IsActive() {
+		app.lockedOut(w, r, l.Until)
+
+		return nil, false
+	}
+
+	return l, true
+}
+
+func (app *application) lockedOut(w http.ResponseWriter, r *http.Request, until time.Time) {
+	msg := fmt.Sprintf("Too many failed login attempts. Please try again after %s or reset your password.",
+		until.Format(time.Kitchen))
+
+	app.tooManyRequests(w, r, time.Until(until), msg)
+}
+
+// Records a failed login and locks out the email once too many consecutive
+// failures have been made. Otherwise, delays before responding with 401.
+func (app *application) loginFailed(w http.ResponseWriter, r *http.Request, email string, lockout *models.Lockout) {
+	err := app.models.AuthAttempt.Insert(r.Context(), "login", app.clientIP(r), email, true)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	// Failures before an expired lockout have already been punished
+	since := time.Now().Add(-rateLimits["login"].window)
+	if lockout != nil && lockout.Until.After(since) {
+		since = lockout.Until
+	}
+
+	failures, err := app.models.AuthAttempt.Failures(r.Context(), email, since)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	if failures >= lockoutThreshold {
+		until := time.Now().Add(lockoutDuration)
+
+		err = app.models.Lockout.Insert(r.Context(), email, until)
+		if err != nil {
+			app.serverError(w, r, err)
+
+			return
+		}
+
+		err = app.sendLockoutEmail(r.Context(), email, until)
+		if err != nil {
+			app.serverError(w, r, err)
+
+			return
+		}
+
+		app.lockedOut(w, r, until)
+
+		return
+	}
+
+	select {
+	case <-time.After(loginDelay(failures)):
+	case <-r.Context().Done():
+		return
+	}
+
+	unauthorizedError(w)
+}
+
+type lockoutEmailData struct {
+	Until string
+	Link  string
+}
+
+func (app *application) sendLockoutEmail(ctx context.Context, email string, until time.Time) error {
+	exists, err := app.models.User.ExistsEmail(ctx, email)
+	if err != nil || !exists {
+		return err
+	}
+
+	ref, err := url.Parse("/auth/reset")
+	if err != nil {
+		return err
+	}
+
+	data := lockoutEmailData{
+		Until: until.Format(time.Kitchen),
+		Link:  app.baseURL.ResolveReference(ref).String(),
+	}
+
+	return app.enqueueEmail(ctx, email, "account_locked.tmpl", data)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/assert"
+)
+
+func TestLoginDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		want     time.Duration
+	}{
+		{
+			name:     "First failure",
+			failures: 1,
+			want:     0,
+		},
+		{
+			name:     "Second failure",
+			failures: 2,
+			want:     0,
+		},
+		{
+			name:     "Third failure",
+			failures: 3,
+			want:     time.Second,
+		},
+		{
+			name:     "Fourth failure",
+			failures: 4,
+			want:     2 * time.Second,
+		},
+		{
+			name:     "Capped",
+			failures: 10,
+			want:     maxLoginDelay,
+		},
+		{
+			name:     "Overflow",
+			failures: 100,
+			want:     maxLoginDelay,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, loginDelay(tt.failures), tt.want)
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		remoteAddr string
+		value      string
+		want       string
+	}{
+		{
+			name:       "Remote address",
+			remoteAddr: "203.0.113.7:5000",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "Untrusted header",
+			remoteAddr: "203.0.113.7:5000",
+			value:      "198.51.100.1",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "Trusted header",
+			header:     "Fly-Client-IP",
+			remoteAddr: "172.16.0.2:5000",
+			value:      "198.51.100.1",
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "Missing header",
+			header:     "Fly-Client-IP",
+			remoteAddr: "172.16.0.2:5000",
+			want:       "172.16.0.2",
+		},
+		{
+			name:       "Invalid header",
+			header:     "Fly-Client-IP",
+			remoteAddr: "172.16.0.2:5000",
+			value:      "not an address",
+			want:       "172.16.0.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &application{clientIPHeader: tt.header}
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.value != "" {
+				r.Header.Set("Fly-Client-IP", tt.value)
+			}
+
+			assert.Equal(t, app.clientIP(r), tt.want)
+		})
+	}
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/micahco/racket-connections/internal/models"
+	"github.com/micahco/racket-connections/internal/validator"
+)
+
+type tournamentsData struct {
+	Tournaments []*models.Tournament
+}
+
+func (app *application) handleTournamentsGet(w http.ResponseWriter, r *http.Request) {
+	t, err := app.models.Tournament.All(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	data := tournamentsData{
+		Tournaments: t,
+	}
+
+	app.render(w, r, http.StatusOK, "tournaments.html", data)
+}
+
+type newTournamentData struct {
+	Sports  []*models.Sport
+	Formats []string
+}
+
+var tournamentFormats = []string{
+	models.TournamentRoundRobin,
+	models.TournamentSingleElimination,
+}
+
+func (app *application) handleTournamentsNewGet(w http.ResponseWriter, r *http.Request) {
+	sports, err := app.models.Sport.All(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	data := newTournamentData{
+		Sports:  sports,
+		Formats: tournamentFormats,
+	}
+
+	app.render(w, r, http.StatusOK, "tournaments-new.html", data)
+}
+
+type newTournamentForm struct {
+	name   string
+	sport  int
+	format string
+	validator.Validator
+}
+
+func (app *application) handleTournamentsNewPost(w http.ResponseWriter, r *http.Request) {
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	sportID, err := strconv.Atoi(r.Form.Get("sport"))
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	form := newTournamentForm{
+		name:   r.Form.Get("name"),
+		sport:  sportID,
+		format: r.Form.Get("format"),
+	}
+
+	sport, err := app.models.Sport.Get(r.Context(), form.sport)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	form.Validate(validator.NotBlank(form.name), "invalid name: cannot be blank")
+	form.Validate(validator.MaxChars(form.name, 100), "invalid name: must be no more than 100 characters long")
+	form.Validate(sport != nil, "invalid sport")
+	form.Validate(form.format == models.TournamentRoundRobin || form.format == models.TournamentSingleElimination, "invalid format")
+
+	if !form.IsValid() {
+		validationError(w, form.Validator)
+
+		return
+	}
+
+	id, err := app.models.Tournament.Insert(r.Context(), form.name, form.format, form.sport, suid)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	http.Redirect(w, r, tournamentURL(id), http.StatusSeeOther)
+}
+
+func tournamentURL(id int) string {
+	return fmt.Sprintf("/tournaments/%d", id)
+}
+
+// Returns the tournament identified by the URL parameter. Writes an error
+// response and returns nil if it does not exist.
+func (app *application) tournament(w http.ResponseWriter, r *http.Request) *models.Tournament {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return nil
+	}
+
+	t, err := app.models.Tournament.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.renderError(w, r, http.StatusNotFound, "")
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return nil
+	}
+
+	return t
+}
+
+type tournamentRound struct {
+	Name    string
+	Matches []*models.TournamentMatch
+}
+
+type tournamentData struct {
+	Tournament   *models.Tournament
+	Players      []*models.TournamentPlayer
+	Rounds       []*tournamentRound
+	UserID       int
+	IsOrganizer  bool
+	IsRegistered bool
+}
+
+// Reports whether the session user may enter the result of the match
+func (d tournamentData) CanReport(m *models.TournamentMatch) bool {
+	return m.IsPending() && (d.IsOrganizer || m.HasPlayer(d.UserID))
+}
+
+func roundName(format string, round, total int) string {
+	if format == models.TournamentSingleElimination {
+		switch total - round {
+		case 0:
+			return "Final"
+		case 1:
+			return "Semifinals"
+		case 2:
+			return "Quarterfinals"
+		}
+	}
+
+	return fmt.Sprintf("Round %d", round)
+}
+
+func (app *application) handleTournamentsIdGet(w http.ResponseWriter, r *http.Request) {
+	t := app.tournament(w, r)
+	if t == nil {
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	players, err := app.models.Tournament.Players(r.Context(), t.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	matches, err := app.models.Tournament.Matches(r.Context(), t.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	isRegistered := false
+	for _, p := range players {
+		if p.UserID == suid {
+			isRegistered = true
+			break
+		}
+	}
+
+	// Matches are ordered by round
+	var rounds []*tournamentRound
+	for _, m := range matches {
+		if len(rounds) < m.Round {
+			rounds = append(rounds, &tournamentRound{})
+		}
+
+		round := rounds[m.Round-1]
+		round.Matches = append(round.Matches, m)
+	}
+
+	for i, round := range rounds {
+		round.Name = roundName(t.Format, i+1, len(rounds))
+	}
+
+	data := tournamentData{
+		Tournament:   t,
+		Players:      players,
+		Rounds:       rounds,
+		UserID:       suid,
+		IsOrganizer:  suid == t.OrganizerID,
+		IsRegistered: isRegistered,
+	}
+
+	app.render(w, r, http.StatusOK, "tournament.html", data)
+}
+
+type tournamentRegistrationEmail struct {
+	Name       string
+	Tournament string
+	Link       string
+}
+
+func (app *application) handleTournamentsIdRegisterPost(w http.ResponseWriter, r *http.Request) {
+	t := app.tournament(w, r)
+	if t == nil {
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	u, err := app.models.User.GetProfile(r.Context(), suid)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	ref, err := url.Parse(tournamentURL(t.ID))
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	email := tournamentRegistrationEmail{
+		Name:       u.Name,
+		Tournament: t.Name,
+		Link:       app.baseURL.ResolveReference(ref).String(),
+	}
+
+	// Register and queue the confirmation together so that a registration
+	// is never left without its email
+	err = app.models.Tx.Do(r.Context(), func(tx models.Models) error {
+		err := tx.Tournament.Register(r.Context(), t.ID, suid)
+		if err != nil {
+			return err
+		}
+
+		return insertEmail(r.Context(), tx, u.Email, "tournament_registration.tmpl", email)
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrDuplicateMember) {
+			http.Redirect(w, r, tournamentURL(t.ID), http.StatusSeeOther)
+		} else if errors.Is(err, models.ErrTournamentStarted) {
+			f := FlashMessage{
+				Type:    FlashError,
+				Message: "Unable to register: registration has closed.",
+			}
+			app.flash(r, f)
+
+			http.Redirect(w, r, tournamentURL(t.ID), http.StatusSeeOther)
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	app.wakeOutbox()
+
+	f := FlashMessage{
+		Type:    FlashSuccess,
+		Message: "Successfully registered. A confirmation has been sent to your email.",
+	}
+	app.flash(r, f)
+
+	http.Redirect(w, r, tournamentURL(t.ID), http.StatusSeeOther)
+}
+
+func (app *application) handleTournamentsIdWithdrawPost(w http.ResponseWriter, r *http.Request) {
+	t := app.tournament(w, r)
+	if t == nil {
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	err = app.models.Tournament.Withdraw(r.Context(), t.ID, suid)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.renderError(w, r, http.StatusBadRequest, "")
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	http.Redirect(w, r, tournamentURL(t.ID), http.StatusSeeOther)
+}
+
+func (app *application) handleTournamentsIdStartPost(w http.ResponseWriter, r *http.Request) {
+	t := app.tournament(w, r)
+	if t == nil {
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil || suid != t.OrganizerID {
+		unauthorizedError(w)
+
+		return
+	}
+
+	err = app.models.Tournament.Start(r.Context(), t.ID, suid)
+	if err != nil {
+		if errors.Is(err, models.ErrNotEnoughPlayers) {
+			f := FlashMessage{
+				Type:    FlashError,
+				Message: "Unable to start: at least two players must register.",
+			}
+			app.flash(r, f)
+
+			http.Redirect(w, r, tournamentURL(t.ID), http.StatusSeeOther)
+		} else if errors.Is(err, models.ErrNoRecord) {
+			app.renderError(w, r, http.StatusBadRequest, "")
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	http.Redirect(w, r, tournamentURL(t.ID), http.StatusSeeOther)
+}
+
+type tournamentResultForm struct {
+	score string
+	validator.Validator
+}
+
+func (app *application) handleTournamentsIdResultPost(w http.ResponseWriter, r *http.Request) {
+	t := app.tournament(w, r)
+	if t == nil {
+		return
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		unauthorizedError(w)
+
+		return
+	}
+
+	matchID, err := strconv.Atoi(chi.URLParam(r, "matchID"))
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	matches, err := app.models.Tournament.Matches(r.Context(), t.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	var match *models.TournamentMatch
+	for _, m := range matches {
+		if m.ID == matchID {
+			match = m
+			break
+		}
+	}
+
+	if match == nil || !match.IsPending() {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	form := tournamentResultForm{
+		score: r.Form.Get("score"),
+	}
+
+	// Scores are entered from the first player's perspective
+	won := false
+	sets, err := models.ParseMatchSets(form.score)
+	if err == nil {
+		won, err = models.ValidateMatchSets(t.Sport, sets)
+	}
+
+	if err != nil {
+		form.Validate(false, "invalid score: "+err.Error())
+	}
+
+	if !form.IsValid() {
+		validationError(w, form.Validator)
+
+		return
+	}
+
+	winnerID := *match.Player2ID
+	if won {
+		winnerID = *match.Player1ID
+	}
+
+	err = app.models.Tournament.Result(r.Context(), t.ID, match.ID, suid, winnerID, form.score)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			unauthorizedError(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	http.Redirect(w, r, tournamentURL(t.ID), http.StatusSeeOther)
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"image/png"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/crypto"
+	"github.com/micahco/racket-connections/internal/models"
+	"github.com/micahco/racket-connections/internal/validator"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	totpIssuer = "Racket Connections"
+	totpPeriod = 30
+	// Codes from adjacent time steps are accepted to allow for clock drift
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+	// Time allowed to enter a code after the password was accepted
+	twoFactorTimeout = 5 * time.Minute
+)
+
+// Returns the time step of the code if it is valid for the secret at the
+// given time.
+func validateTOTP(secret, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	step := t.Unix() / totpPeriod
+
+	for i := -totpSkew; i <= totpSkew; i++ {
+		s := step + int64(i)
+
+		want, err := totp.GenerateCode(secret, time.Unix(s*totpPeriod, 0))
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(code), []byte(want)) == 1 {
+			return s, true
+		}
+	}
+
+	return 0, false
+}
+
+// Returns recovery codes in the form xxxxx-xxxxx
+func generateRecoveryCodes(n int) ([]string, error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+	// Drawn uniformly; a byte modulo the alphabet length would favour the
+	// first few characters
+	size := big.NewInt(int64(len(alphabet)))
+
+	codes := make([]string, n)
+	for i := range codes {
+		var sb strings.Builder
+		for j := 0; j < 10; j++ {
+			if j == 5 {
+				sb.WriteByte('-')
+			}
+
+			c, err := rand.Int(rand.Reader, size)
+			if err != nil {
+				return nil, err
+			}
+			sb.WriteByte(alphabet[c.Int64()])
+		}
+
+		codes[i] = sb.String()
+	}
+
+	return codes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+
+	return crypto.HashToken(code)
+}
+
+// Reports whether the code is a current TOTP code or an unused recovery code
+// for the user. Accepted codes cannot be used again.
+func (app *application) verifyTwoFactor(ctx context.Context, userID int, code string) (bool, error) {
+	t, err := app.models.TOTP.Get(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := validateTOTP(t.Secret, code, time.Now())
+	if ok {
+		err = app.models.TOTP.UseStep(ctx, userID, step)
+		if errors.Is(err, models.ErrNoRecord) {
+			return false, nil
+		}
+
+		return err == nil, err
+	}
+
+	err = app.models.TOTP.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	if errors.Is(err, models.ErrNoRecord) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Stores the user as awaiting a second factor instead of logging them in
+func (app *application) beginTwoFactor(r *http.Request, userID int) error {
+	err := app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		return err
+	}
+
+	app.sessionManager.Put(r.Context(), twoFactorUserIDSessionKey, userID)
+	app.sessionManager.Put(r.Context(), twoFactorExpirySessionKey, time.Now().Add(twoFactorTimeout))
+
+	return nil
+}
+
+// Returns the ID of the user awaiting a second factor, or 0 if there is none
+// or the time to enter a code has passed.
+func (app *application) twoFactorUserID(r *http.Request) int {
+	expiry := app.sessionManager.GetTime(r.Context(), twoFactorExpirySessionKey)
+	if time.Now().After(expiry) {
+		return 0
+	}
+
+	return app.sessionManager.GetInt(r.Context(), twoFactorUserIDSessionKey)
+}
+
+func (app *application) handleAuthTwoFactorGet(w http.ResponseWriter, r *http.Request) {
+	if app.twoFactorUserID(r) == 0 {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+
+		return
+	}
+
+	app.render(w, r, http.StatusOK, "auth-2fa.html", nil)
+}
+
+type authTwoFactorForm struct {
+	code string
+	validator.Validator
+}
+
+func (app *application) handleAuthTwoFactorPost(w http.ResponseWriter, r *http.Request) {
+	userID := app.twoFactorUserID(r)
+	if userID == 0 {
+		unauthorizedError(w)
+
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	form := authTwoFactorForm{code: r.Form.Get("code")}
+
+	form.Validate(validator.NotBlank(form.code), "invalid code: cannot be blank")
+	form.Validate(validator.MaxChars(form.code, 20), "invalid code: must be no more than 20 characters long")
+
+	if !form.IsValid() {
+		validationError(w, form.Validator)
+
+		return
+	}
+
+	u, err := app.models.User.GetProfile(r.Context(), userID)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	if !app.allowAttempt(w, r, "2fa", u.Email) {
+		return
+	}
+
+	ok, err := app.verifyTwoFactor(r.Context(), userID, form.code)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	if !ok {
+		unauthorizedError(w)
+
+		return
+	}
+
+	app.sessionManager.Remove(r.Context(), twoFactorUserIDSessionKey)
+	app.sessionManager.Remove(r.Context(), twoFactorExpirySessionKey)
+
+	err = app.login(r, userID)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+type profileTwoFactorData struct {
+	IsEnabled     bool
+	RecoveryCodes int
+	Secret        string
+	// Shown once after enrollment
+	NewRecoveryCodes []string
+}
+
+func (app *application) handleProfileTwoFactorGet(w http.ResponseWriter, r *http.Request) {
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	enabled, err := app.models.TOTP.Exists(r.Context(), suid)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	if enabled {
+		n, err := app.models.TOTP.RecoveryCodes(r.Context(), suid)
+		if err != nil {
+			app.serverError(w, r, err)
+
+			return
+		}
+
+		data := profileTwoFactorData{
+			IsEnabled:     true,
+			RecoveryCodes: n,
+		}
+
+		app.render(w, r, http.StatusOK, "profile-2fa.html", data)
+
+		return
+	}
+
+	u, err := app.models.User.GetProfile(r.Context(), suid)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer,
+		AccountName: u.Email,
+		Period:      totpPeriod,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	// Kept in the session until the user confirms a code
+	app.sessionManager.Put(r.Context(), totpKeySessionKey, key.String())
+
+	data := profileTwoFactorData{
+		Secret: key.Secret(),
+	}
+
+	app.render(w, r, http.StatusOK, "profile-2fa.html", data)
+}
+
+func (app *application) handleProfileTwoFactorQRGet(w http.ResponseWriter, r *http.Request) {
+	key, err := otp.NewKeyFromURL(app.sessionManager.GetString(r.Context(), totpKeySessionKey))
+	if err != nil {
+		app.renderError(w, r, http.StatusNotFound, "")
+
+		return
+	}
+
+	img, err := key.Image(256, 256)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+
+	err = png.Encode(w, img)
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), err.Error())
+	}
+}
+
+type profileTwoFactorForm struct {
+	code string
+	validator.Validator
+}
+
+func (app *application) handleProfileTwoFactorEnablePost(w http.ResponseWriter, r *http.Request) {
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	key, err := otp.NewKeyFromURL(app.sessionManager.GetString(r.Context(), totpKeySessionKey))
+	if err != nil {
+		http.Redirect(w, r, "/profile/2fa", http.StatusSeeOther)
+
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	form := profileTwoFactorForm{code: r.Form.Get("code")}
+
+	step, ok := validateTOTP(key.Secret(), form.code, time.Now())
+	form.Validate(ok, "invalid code: does not match the authenticator app")
+
+	if !form.IsValid() {
+		validationError(w, form.Validator)
+
+		return
+	}
+
+	codes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = hashRecoveryCode(c)
+	}
+
+	err = app.models.TOTP.Enable(r.Context(), suid, key.Secret(), step, hashes)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	app.sessionManager.Remove(r.Context(), totpKeySessionKey)
+
+	data := profileTwoFactorData{
+		IsEnabled:        true,
+		RecoveryCodes:    len(codes),
+		NewRecoveryCodes: codes,
+	}
+
+	app.render(w, r, http.StatusOK, "profile-2fa.html", data)
+}
+
+func (app *application) handleProfileTwoFactorDisablePost(w http.ResponseWriter, r *http.Request) {
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.renderError(w, r, http.StatusBadRequest, "")
+
+		return
+	}
+
+	form := profileTwoFactorForm{code: r.Form.Get("code")}
+
+	form.Validate(validator.NotBlank(form.code), "invalid code: cannot be blank")
+	form.Validate(validator.MaxChars(form.code, 20), "invalid code: must be no more than 20 characters long")
+
+	if !form.IsValid() {
+		validationError(w, form.Validator)
+
+		return
+	}
+
+	ok, err := app.verifyTwoFactor(r.Context(), suid, form.code)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.renderError(w, r, http.StatusBadRequest, "")
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	if !ok {
+		unauthorizedError(w)
+
+		return
+	}
+
+	err = app.models.TOTP.Disable(r.Context(), suid)
+	if err != nil {
+		app.serverError(w, r, err)
+
+		return
+	}
+
+	f := FlashMessage{
+		Type:    FlashSuccess,
+		Message: "Two-factor authentication disabled.",
+	}
+	app.flash(r, f)
+
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/assert"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestValidateTOTP(t *testing.T) {
+	// RFC 6238 test secret "12345678901234567890" encoded as base32
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	now := time.Unix(59, 0)
+
+	code, err := totp.GenerateCode(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous, err := totp.GenerateCode(secret, now.Add(-totpPeriod*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		at       time.Time
+		wantStep int64
+		wantOK   bool
+	}{
+		{
+			name:     "Current",
+			code:     code,
+			at:       now,
+			wantStep: 1,
+			wantOK:   true,
+		},
+		{
+			name:     "Whitespace",
+			code:     " " + code + " ",
+			at:       now,
+			wantStep: 1,
+			wantOK:   true,
+		},
+		{
+			name:     "Previous step",
+			code:     previous,
+			at:       now,
+			wantStep: 0,
+			wantOK:   true,
+		},
+		{
+			name:   "Expired",
+			code:   code,
+			at:     now.Add(5 * time.Minute),
+			wantOK: false,
+		},
+		{
+			name:   "Wrong",
+			code:   "000000",
+			at:     now,
+			wantOK: code == "000000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := validateTOTP(secret, tt.code, tt.at)
+			assert.Equal(t, ok, tt.wantOK)
+			if ok {
+				assert.Equal(t, step, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	codes, err := generateRecoveryCodes(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(codes[0]), 11)
+	assert.Equal(t, hashRecoveryCode(codes[0]), hashRecoveryCode(" "+codes[0]+" "))
+	assert.Equal(t, hashRecoveryCode("abcde-fghjk"), hashRecoveryCode("ABCDEFGHJK"))
+	assert.Equal(t, hashRecoveryCode(codes[0]) == hashRecoveryCode(codes[1]), false)
+}
@@ -2,7 +2,9 @@ package crypto
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 )
 
 // https://blog.questionable.services/article/generating-secure-random-numbers-crypto-rand/
@@ -20,3 +22,10 @@ func GenerateRandomString(n int) (string, error) {
 	b, err := GenerateRandomBytes(n)
 	return base64.URLEncoding.EncodeToString(b), err
 }
+
+// Returns the hex encoded SHA-256 digest of s. Suitable for storing high
+// entropy secrets, such as tokens and recovery codes, that must be looked up.
+func HashToken(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,170 @@
+package crypto
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrMismatchedPassword = errors.New("crypto: password does not match hash")
+	ErrUnknownHash        = errors.New("crypto: unknown password hash format")
+)
+
+// Hashes passwords for storage. Hashes are self-describing, encoding their
+// algorithm and parameters, so hashes produced by any supported hasher can be
+// verified with ComparePassword.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+
+	// Reports whether the hash was produced by a different algorithm or with
+	// weaker parameters than the hasher is configured with.
+	NeedsRehash(hash string) bool
+}
+
+// Compares a password with a hash produced by any supported hasher. Returns
+// ErrMismatchedPassword if they do not match.
+func ComparePassword(hash, password string) error {
+	switch {
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatchedPassword
+		}
+
+		return err
+	case isArgon2id(hash):
+		p, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return err
+		}
+
+		other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return ErrMismatchedPassword
+		}
+
+		return nil
+	}
+
+	return ErrUnknownHash
+}
+
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+
+	return string(hash), err
+}
+
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	if !isBcrypt(hash) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+
+	return err != nil || cost < h.Cost
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") ||
+		strings.HasPrefix(hash, "$2b$") ||
+		strings.HasPrefix(hash, "$2y$")
+}
+
+// Hashes passwords with argon2id. Hashes are encoded in the PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+type Argon2idHasher struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Returns a hasher with the parameters recommended by RFC 9106 for
+// memory-constrained environments
+func NewArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 4,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt, err := GenerateRandomBytes(int(h.SaltLength))
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(hash string) bool {
+	if !isArgon2id(hash) {
+		return true
+	}
+
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+
+	return p.Memory < h.Memory ||
+		p.Iterations < h.Iterations ||
+		p.Parallelism < h.Parallelism ||
+		uint32(len(salt)) < h.SaltLength ||
+		uint32(len(key)) < h.KeyLength
+}
+
+func isArgon2id(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func decodeArgon2id(hash string) (*Argon2idHasher, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, nil, nil, ErrUnknownHash
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrUnknownHash
+	}
+
+	var p Argon2idHasher
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism)
+	if err != nil {
+		return nil, nil, nil, ErrUnknownHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrUnknownHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, ErrUnknownHash
+	}
+
+	return &p, salt, key, nil
+}
@@ -0,0 +1,106 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/micahco/racket-connections/internal/assert"
+)
+
+// Cheap parameters keep the tests fast
+func testArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{
+		Memory:      64,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+func TestComparePassword(t *testing.T) {
+	hashers := []struct {
+		name   string
+		hasher PasswordHasher
+	}{
+		{
+			name:   "Bcrypt",
+			hasher: &BcryptHasher{Cost: 4},
+		},
+		{
+			name:   "Argon2id",
+			hasher: testArgon2idHasher(),
+		},
+	}
+
+	for _, h := range hashers {
+		t.Run(h.name, func(t *testing.T) {
+			hash, err := h.hasher.Hash("correct horse")
+			assert.Equal(t, err, nil)
+
+			assert.Equal(t, ComparePassword(hash, "correct horse"), nil)
+			assert.Equal(t, ComparePassword(hash, "battery staple"), ErrMismatchedPassword)
+			assert.Equal(t, h.hasher.NeedsRehash(hash), false)
+		})
+	}
+
+	assert.Equal(t, ComparePassword("plaintext", "plaintext"), ErrUnknownHash)
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcrypt4, _ := (&BcryptHasher{Cost: 4}).Hash("password")
+	bcrypt5, _ := (&BcryptHasher{Cost: 5}).Hash("password")
+	argon, _ := testArgon2idHasher().Hash("password")
+
+	stronger := testArgon2idHasher()
+	stronger.Iterations = 2
+
+	tests := []struct {
+		name   string
+		hasher PasswordHasher
+		hash   string
+		want   bool
+	}{
+		{
+			name:   "Bcrypt weaker cost",
+			hasher: &BcryptHasher{Cost: 5},
+			hash:   bcrypt4,
+			want:   true,
+		},
+		{
+			name:   "Bcrypt stronger cost",
+			hasher: &BcryptHasher{Cost: 4},
+			hash:   bcrypt5,
+			want:   false,
+		},
+		{
+			name:   "Bcrypt to argon2id",
+			hasher: testArgon2idHasher(),
+			hash:   bcrypt4,
+			want:   true,
+		},
+		{
+			name:   "Argon2id to bcrypt",
+			hasher: &BcryptHasher{Cost: 4},
+			hash:   argon,
+			want:   true,
+		},
+		{
+			name:   "Argon2id weaker iterations",
+			hasher: stronger,
+			hash:   argon,
+			want:   true,
+		},
+		{
+			name:   "Malformed argon2id",
+			hasher: testArgon2idHasher(),
+			hash:   "$argon2id$v=19$m=64,t=1,p=1$salt",
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.hasher.NeedsRehash(tt.hash), tt.want)
+		})
+	}
+}
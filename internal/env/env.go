@@ -3,6 +3,8 @@ package env
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 )
 
 type EnvVarNotSetError struct {
@@ -21,3 +23,46 @@ func Get(key string) (string, error) {
 
 	return val, nil
 }
+
+// Returns the value of the environment variable, or fallback if it is not set
+// or empty.
+func GetOr(key, fallback string) string {
+	val, ok := os.LookupEnv(key)
+	if !ok || val == "" {
+		return fallback
+	}
+
+	return val
+}
+
+// Returns the integer value of the environment variable, or fallback if it is
+// not set or empty.
+func GetInt(key string, fallback int) (int, error) {
+	val := GetOr(key, "")
+	if val == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("environment variable %s: %w", key, err)
+	}
+
+	return n, nil
+}
+
+// Returns the duration value of the environment variable, such as "5s", or
+// fallback if it is not set or empty.
+func GetDuration(key string, fallback time.Duration) (time.Duration, error) {
+	val := GetOr(key, "")
+	if val == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("environment variable %s: %w", key, err)
+	}
+
+	return d, nil
+}
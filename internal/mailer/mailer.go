@@ -2,59 +2,120 @@ package mailer
 
 import (
 	"bytes"
+	"crypto/rand"
 	"embed"
+	"encoding/hex"
 	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
 	"net/mail"
-	"strconv"
+	"strings"
 	"text/template"
-
-	"gopkg.in/gomail.v2"
 )
 
 //go:embed "templates"
 var templateFS embed.FS
 
 type Mailer struct {
-	dialer *gomail.Dialer
-	sender *mail.Address
+	transport Transport
+	sender    *mail.Address
 }
 
-func New(host, port, username, password string, sender *mail.Address) (*Mailer, error) {
-	smtpPort, err := strconv.Atoi(port)
-	if err != nil {
-		return nil, err
+func New(transport Transport, sender *mail.Address) *Mailer {
+	return &Mailer{
+		transport: transport,
+		sender:    sender,
 	}
+}
 
-	m := &Mailer{
-		dialer: gomail.NewDialer(host, smtpPort, username, password),
-		sender: sender,
-	}
-	return m, nil
+// A rendered email, ready to be delivered
+type Message struct {
+	ID        string // Message-ID header, assigned on delivery
+	From      string // assigned on delivery
+	Recipient string
+	Subject   string
+	Body      string
+	HTMLBody  string // optional alternative to the plain text body
 }
 
-func (m *Mailer) Send(recepient, templateFile string, data interface{}) error {
+// Returns the names of the email templates
+func Templates() ([]string, error) {
+	return fs.Glob(templateFS, "templates/*.tmpl")
+}
+
+// Renders the subject and body templates defined in templateFile. If it also
+// defines an htmlBody template, it is rendered within the shared HTML layout.
+func Render(recepient, templateFile string, data interface{}) (*Message, error) {
 	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
 	if err != nil {
-		return fmt.Errorf("1: %s", err.Error())
+		return nil, err
 	}
 
 	subject := new(bytes.Buffer)
 	err = tmpl.ExecuteTemplate(subject, "subject", data)
 	if err != nil {
-		return fmt.Errorf("2: %s", err.Error())
+		return nil, err
 	}
 
 	body := new(bytes.Buffer)
 	err = tmpl.ExecuteTemplate(body, "body", data)
 	if err != nil {
-		return fmt.Errorf("3: %s", err.Error())
+		return nil, err
+	}
+
+	msg := &Message{
+		Recipient: recepient,
+		Subject:   subject.String(),
+		Body:      body.String(),
+	}
+
+	if tmpl.Lookup("htmlBody") == nil {
+		return msg, nil
+	}
+
+	// Parsed again as HTML so that data is escaped for its context
+	ht, err := htmltemplate.New("email").ParseFS(templateFS, "templates/layout.html", "templates/"+templateFile)
+	if err != nil {
+		return nil, err
 	}
 
-	msg := gomail.NewMessage()
-	msg.SetHeader("To", recepient)
-	msg.SetHeader("From", m.sender.String())
-	msg.SetHeader("Subject", subject.String())
-	msg.SetBody("text/plain", body.String())
+	htmlBody := new(bytes.Buffer)
+	err = ht.ExecuteTemplate(htmlBody, "layout", data)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.HTMLBody = htmlBody.String()
+
+	return msg, nil
+}
+
+// Delivers the message with the mailer's transport. Returns the Message-ID
+// header assigned to it, which identifies the message in the provider's logs
+// and bounces.
+func (m *Mailer) Deliver(msg *Message) (string, error) {
+	id, err := m.messageID()
+	if err != nil {
+		return "", err
+	}
+
+	msg.ID = id
+	msg.From = m.sender.String()
+
+	return id, m.transport.Send(msg)
+}
+
+func (m *Mailer) messageID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if i := strings.LastIndex(m.sender.Address, "@"); i >= 0 {
+		domain = m.sender.Address[i+1:]
+	}
 
-	return m.dialer.DialAndSend(msg)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain), nil
 }
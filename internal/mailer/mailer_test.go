@@ -0,0 +1,88 @@
+package mailer
+
+import (
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/micahco/racket-connections/internal/assert"
+)
+
+func TestRender(t *testing.T) {
+	link := `https://example.com/?token=a"><script>`
+
+	msg, err := Render("benny@oregonstate.edu", "email_verification.tmpl", link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, msg.Subject, "Email verification")
+	assert.Equal(t, strings.Contains(msg.Body, link), true)
+	assert.Equal(t, strings.Contains(msg.HTMLBody, "<!DOCTYPE html>"), true)
+	assert.Equal(t, strings.Contains(msg.HTMLBody, "<script>"), false)
+}
+
+func TestDeliver(t *testing.T) {
+	mt := NewMemoryTransport()
+	m := New(mt, &mail.Address{Name: "Racket Connections", Address: "no-reply@example.com"})
+
+	msg, err := Render("benny@oregonstate.edu", "login_link.tmpl", "https://example.com/auth/link")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := m.Deliver(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := mt.Last("benny@oregonstate.edu")
+	if sent == nil {
+		t.Fatal("message not sent")
+	}
+
+	assert.Equal(t, sent.ID, id)
+	assert.Equal(t, strings.HasSuffix(id, "@example.com>"), true)
+	assert.Equal(t, sent.From, `"Racket Connections" <no-reply@example.com>`)
+	assert.Equal(t, sent.Subject, "Login link")
+	assert.Equal(t, len(mt.Messages()), 1)
+}
+
+func TestFileTransport(t *testing.T) {
+	dir := t.TempDir()
+
+	ft, err := NewFileTransport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(ft, &mail.Address{Address: "no-reply@example.com"})
+
+	msg, err := Render("benny@oregonstate.edu", "email_verification.tmpl", "https://example.com/auth/register")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.Deliver(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(files), 1)
+
+	b, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eml := string(b)
+	assert.Equal(t, strings.Contains(eml, "To: benny@oregonstate.edu"), true)
+	assert.Equal(t, strings.Contains(eml, "multipart/alternative"), true)
+}
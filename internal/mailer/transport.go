@@ -0,0 +1,135 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+// Delivers composed messages
+type Transport interface {
+	Send(msg *Message) error
+}
+
+func compose(msg *Message) *gomail.Message {
+	gm := gomail.NewMessage()
+	gm.SetHeader("Message-ID", msg.ID)
+	gm.SetHeader("To", msg.Recipient)
+	gm.SetHeader("From", msg.From)
+	gm.SetHeader("Subject", msg.Subject)
+	gm.SetBody("text/plain", msg.Body)
+
+	if msg.HTMLBody != "" {
+		gm.AddAlternative("text/html", msg.HTMLBody)
+	}
+
+	return gm
+}
+
+// Sends messages to an SMTP server
+type SMTPTransport struct {
+	dialer *gomail.Dialer
+}
+
+func NewSMTPTransport(host, port, username, password string) (*SMTPTransport, error) {
+	smtpPort, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &SMTPTransport{
+		dialer: gomail.NewDialer(host, smtpPort, username, password),
+	}
+
+	return t, nil
+}
+
+func (t *SMTPTransport) Send(msg *Message) error {
+	return t.dialer.DialAndSend(compose(msg))
+}
+
+// Writes messages to a directory as .eml files, which can be opened with
+// most mail clients
+type FileTransport struct {
+	dir string
+}
+
+func NewFileTransport(dir string) (*FileTransport, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileTransport{dir}, nil
+}
+
+func (t *FileTransport) Send(msg *Message) error {
+	// Message IDs look like <hex@domain>
+	id := strings.Trim(msg.ID, "<>")
+	if i := strings.Index(id, "@"); i >= 0 {
+		id = id[:i]
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405"), id)
+
+	f, err := os.Create(filepath.Join(t.dir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = compose(msg).WriteTo(f)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// Keeps messages in memory for tests to inspect
+type MemoryTransport struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+func (t *MemoryTransport) Send(msg *Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m := *msg
+	t.messages = append(t.messages, &m)
+
+	return nil
+}
+
+// Returns the messages sent so far, oldest first
+func (t *MemoryTransport) Messages() []*Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]*Message(nil), t.messages...)
+}
+
+// Returns the most recent message sent to the recipient, or nil
+func (t *MemoryTransport) Last(recipient string) *Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := len(t.messages) - 1; i >= 0; i-- {
+		if t.messages[i].Recipient == recipient {
+			return t.messages[i]
+		}
+	}
+
+	return nil
+}
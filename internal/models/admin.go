@@ -0,0 +1,23 @@
+package models
+
+import (
+	"context"
+)
+
+type AdminModelInterface interface {
+	Exists(ctx context.Context, userID int) (bool, error)
+}
+
+type AdminModel struct {
+	db dbtx
+}
+
+func (m *AdminModel) Exists(ctx context.Context, userID int) (bool, error) {
+	var exists bool
+
+	sql := "SELECT EXISTS(SELECT true FROM admin_ WHERE user_id_ = $1);"
+
+	err := m.db.QueryRow(ctx, sql, userID).Scan(&exists)
+
+	return exists, err
+}
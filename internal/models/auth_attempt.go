@@ -0,0 +1,70 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// Attempts older than this are purged as new ones are recorded
+const authAttemptRetention = 24 * time.Hour
+
+type AuthAttemptModelInterface interface {
+	Insert(ctx context.Context, action, ip, email string, failed bool) error
+	Count(ctx context.Context, action, ip, email string, since time.Time) (int, int, error)
+	Failures(ctx context.Context, email string, since time.Time) (int, error)
+}
+
+type AuthAttemptModel struct {
+	db dbtx
+}
+
+// Records an attempt at an authentication action such as "login", "signup"
+// or "reset" and purges attempts that have aged out of every window.
+func (m *AuthAttemptModel) Insert(ctx context.Context, action, ip, email string, failed bool) error {
+	sql := `WITH purged AS (
+			DELETE FROM auth_attempt_ WHERE created_at_ < $5
+		)
+		INSERT INTO auth_attempt_
+		(action_, ip_, email_, failed_)
+		VALUES($1, $2, $3, $4);`
+
+	_, err := m.db.Exec(ctx, sql,
+		action, ip, email, failed, time.Now().Add(-authAttemptRetention))
+
+	return err
+}
+
+// Returns the number of attempts at the action made since the given time
+// from the IP address and for the email.
+func (m *AuthAttemptModel) Count(ctx context.Context, action, ip, email string, since time.Time) (int, int, error) {
+	var byIP, byEmail int
+
+	sql := `SELECT
+			COUNT(*) FILTER (WHERE ip_ = $2),
+			COUNT(*) FILTER (WHERE email_ = $3)
+		FROM auth_attempt_
+		WHERE action_ = $1 AND created_at_ > $4
+		AND (ip_ = $2 OR email_ = $3);`
+
+	err := m.db.QueryRow(ctx, sql,
+		action, ip, email, since).Scan(&byIP, &byEmail)
+
+	return byIP, byEmail, err
+}
+
+// Returns the number of failed logins for the email since the given time that
+// have not been followed by a successful one.
+func (m *AuthAttemptModel) Failures(ctx context.Context, email string, since time.Time) (int, error) {
+	var n int
+
+	sql := `SELECT COUNT(*) FROM auth_attempt_
+		WHERE action_ = 'login' AND email_ = $1 AND failed_
+		AND created_at_ > GREATEST($2, (
+			SELECT MAX(created_at_) FROM auth_attempt_
+			WHERE action_ = 'login' AND email_ = $1 AND NOT failed_
+		));`
+
+	err := m.db.QueryRow(ctx, sql, email, since).Scan(&n)
+
+	return n, err
+}
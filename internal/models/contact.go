@@ -5,30 +5,38 @@ import (
 	"errors"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+type ContactModelInterface interface {
+	Insert(ctx context.Context, value string, userID, methodID int) error
+	Exists(ctx context.Context, userID int) (bool, error)
+	Methods(ctx context.Context) ([]*ContactMethod, error)
+	UserContacts(ctx context.Context, userID int) ([]*UserContact, error)
+	MethodID(ctx context.Context, name string) (int, error)
+	Delete(ctx context.Context, id int) error
+}
+
 type ContactModel struct {
-	pool *pgxpool.Pool
+	db dbtx
 }
 
-func (m *ContactModel) Insert(value string, userID, methodID int) error {
+func (m *ContactModel) Insert(ctx context.Context, value string, userID, methodID int) error {
 	sql := `INSERT INTO contact_ 
 		(value_, user_id_, contact_method_id_)
 		VALUES($1, $2, $3);`
 
-	_, err := m.pool.Exec(context.Background(), sql,
+	_, err := m.db.Exec(ctx, sql,
 		value, userID, methodID)
 
 	return err
 }
 
-func (m *ContactModel) Exists(userID int) (bool, error) {
+func (m *ContactModel) Exists(ctx context.Context, userID int) (bool, error) {
 	var exists bool
 
 	sql := "SELECT EXISTS(SELECT true FROM contact_ WHERE user_id_ = $1);"
 
-	err := m.pool.QueryRow(context.Background(), sql, userID).Scan(&exists)
+	err := m.db.QueryRow(ctx, sql, userID).Scan(&exists)
 
 	return exists, err
 }
@@ -45,10 +53,10 @@ func scanContactMethod(row pgx.CollectableRow) (*ContactMethod, error) {
 	return &s, err
 }
 
-func (m *ContactModel) Methods() ([]*ContactMethod, error) {
+func (m *ContactModel) Methods(ctx context.Context) ([]*ContactMethod, error) {
 	sql := "SELECT * FROM contact_method_;"
 
-	rows, err := m.pool.Query(context.Background(), sql)
+	rows, err := m.db.Query(ctx, sql)
 	if err != nil {
 		return nil, err
 	}
@@ -69,7 +77,7 @@ func scanUserContact(row pgx.CollectableRow) (*UserContact, error) {
 	return &c, err
 }
 
-func (m *ContactModel) UserContacts(userID int) ([]*UserContact, error) {
+func (m *ContactModel) UserContacts(ctx context.Context, userID int) ([]*UserContact, error) {
 	sql := `SELECT
 			c.id_,
 			c.value_,
@@ -79,7 +87,7 @@ func (m *ContactModel) UserContacts(userID int) ([]*UserContact, error) {
 			ON c.contact_method_id_ = m.id_
 		WHERE c.user_id_ = $1;`
 
-	rows, err := m.pool.Query(context.Background(), sql, userID)
+	rows, err := m.db.Query(ctx, sql, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -96,20 +104,20 @@ func (m *ContactModel) UserContacts(userID int) ([]*UserContact, error) {
 	return c, nil
 }
 
-func (m *ContactModel) MethodID(name string) (int, error) {
+func (m *ContactModel) MethodID(ctx context.Context, name string) (int, error) {
 	var id int
 
 	sql := "SELECT id_ FROM contact_method_ WHERE name_ = $1;"
 
-	err := m.pool.QueryRow(context.Background(), sql, name).Scan(&id)
+	err := m.db.QueryRow(ctx, sql, name).Scan(&id)
 
 	return id, err
 }
 
-func (m *ContactModel) Delete(id int) error {
+func (m *ContactModel) Delete(ctx context.Context, id int) error {
 	sql := "DELETE FROM contact_ WHERE id_ = $1;"
 
-	_, err := m.pool.Exec(context.Background(), sql, id)
+	_, err := m.db.Exec(ctx, sql, id)
 
 	return err
 }
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Wraps the pool so every query runs with a timeout on top of the caller's
+// context. Queries that run out of time return ErrTimeout.
+type db struct {
+	pool    *pgxpool.Pool
+	timeout time.Duration
+}
+
+func timeoutError(err error) error {
+	if err == nil || errors.Is(err, ErrTimeout) {
+		return err
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || pgErrCode(err) == pgerrcode.QueryCanceled {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+
+	return err
+}
+
+func (d *db) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return exec(ctx, d.pool, d.timeout, sql, args...)
+}
+
+func (d *db) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return query(ctx, d.pool, d.timeout, sql, args...)
+}
+
+func (d *db) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return queryRow(ctx, d.pool, d.timeout, sql, args...)
+}
+
+// Each statement in the transaction gets its own timeout
+func (d *db) Begin(ctx context.Context) (*dbTx, error) {
+	qctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	t, err := d.pool.Begin(qctx)
+	if err != nil {
+		return nil, timeoutError(err)
+	}
+
+	return &dbTx{t, d.timeout}, nil
+}
+
+// A transaction whose statements time out like the pool's
+type dbTx struct {
+	pgx.Tx
+	timeout time.Duration
+}
+
+// Begins a nested transaction using a savepoint
+func (t *dbTx) Begin(ctx context.Context) (*dbTx, error) {
+	qctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	nested, err := t.Tx.Begin(qctx)
+	if err != nil {
+		return nil, timeoutError(err)
+	}
+
+	return &dbTx{nested, t.timeout}, nil
+}
+
+func (t *dbTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return exec(ctx, t.Tx, t.timeout, sql, args...)
+}
+
+func (t *dbTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return query(ctx, t.Tx, t.timeout, sql, args...)
+}
+
+func (t *dbTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return queryRow(ctx, t.Tx, t.timeout, sql, args...)
+}
+
+func (t *dbTx) Commit(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	return timeoutError(t.Tx.Commit(ctx))
+}
+
+func (t *dbTx) Rollback(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	return timeoutError(t.Tx.Rollback(ctx))
+}
+
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Models run against either the pool or a transaction
+type dbtx interface {
+	querier
+	Begin(ctx context.Context) (*dbTx, error)
+}
+
+func exec(ctx context.Context, q querier, timeout time.Duration, sql string, args ...any) (pgconn.CommandTag, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tag, err := q.Exec(ctx, sql, args...)
+
+	return tag, timeoutError(err)
+}
+
+// The timeout is cancelled once the rows are closed
+func query(ctx context.Context, q querier, timeout time.Duration, sql string, args ...any) (pgx.Rows, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+
+	r, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		cancel()
+
+		return nil, timeoutError(err)
+	}
+
+	return &rows{r, cancel}, nil
+}
+
+func queryRow(ctx context.Context, q querier, timeout time.Duration, sql string, args ...any) pgx.Row {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+
+	return &row{q.QueryRow(ctx, sql, args...), cancel}
+}
+
+type rows struct {
+	pgx.Rows
+	cancel context.CancelFunc
+}
+
+func (r *rows) Close() {
+	r.Rows.Close()
+	r.cancel()
+}
+
+func (r *rows) Err() error {
+	return timeoutError(r.Rows.Err())
+}
+
+type row struct {
+	pgx.Row
+	cancel context.CancelFunc
+}
+
+func (r *row) Scan(dest ...any) error {
+	defer r.cancel()
+
+	return timeoutError(r.Row.Scan(dest...))
+}
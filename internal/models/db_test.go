@@ -0,0 +1,52 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/micahco/racket-connections/internal/assert"
+)
+
+func TestTimeoutError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "Deadline exceeded",
+			err:  fmt.Errorf("timeout: %w", context.DeadlineExceeded),
+			want: true,
+		},
+		{
+			name: "Statement cancelled",
+			err:  &pgconn.PgError{Code: pgerrcode.QueryCanceled},
+			want: true,
+		},
+		{
+			name: "Caller cancelled",
+			err:  context.Canceled,
+			want: false,
+		},
+		{
+			name: "No rows",
+			err:  pgx.ErrNoRows,
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := timeoutError(tt.err)
+			assert.Equal(t, errors.Is(err, ErrTimeout), tt.want)
+			assert.Equal(t, errors.Is(err, tt.err), true)
+		})
+	}
+
+	assert.Equal(t, timeoutError(nil), nil)
+}
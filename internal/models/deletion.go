@@ -0,0 +1,143 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type DeletionModelInterface interface {
+	Request(ctx context.Context, userID int, purgeAt time.Time) error
+	Get(ctx context.Context, userID int) (*Deletion, error)
+	Cancel(ctx context.Context, userID int) (bool, error)
+	Due(ctx context.Context, limit int) ([]*Deletion, error)
+	Purge(ctx context.Context, userID int) error
+}
+
+// Accounts the users have asked to delete. The accounts are kept until the
+// purge time in case the user changes their mind.
+type DeletionModel struct {
+	db dbtx
+}
+
+type Deletion struct {
+	UserID      int
+	Name        string
+	Email       string
+	RequestedAt time.Time
+	PurgeAt     time.Time
+}
+
+func scanDeletion(row pgx.CollectableRow) (*Deletion, error) {
+	var d Deletion
+	err := row.Scan(
+		&d.UserID,
+		&d.Name,
+		&d.Email,
+		&d.RequestedAt,
+		&d.PurgeAt)
+
+	return &d, err
+}
+
+// Schedules the user's account to be purged. Requesting again does not
+// change the purge time.
+func (m *DeletionModel) Request(ctx context.Context, userID int, purgeAt time.Time) error {
+	sql := `INSERT INTO deletion_ (user_id_, purge_at_)
+		VALUES($1, $2)
+		ON CONFLICT (user_id_) DO NOTHING;`
+
+	_, err := m.db.Exec(ctx, sql, userID, purgeAt)
+
+	return err
+}
+
+func (m *DeletionModel) Get(ctx context.Context, userID int) (*Deletion, error) {
+	sql := `SELECT
+			d.user_id_,
+			u.name_,
+			u.email_,
+			d.requested_at_,
+			d.purge_at_
+		FROM deletion_ d
+		INNER JOIN user_ u
+			ON u.id_ = d.user_id_
+		WHERE d.user_id_ = $1;`
+
+	rows, err := m.db.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := pgx.CollectOneRow(rows, scanDeletion)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+
+	return d, err
+}
+
+// Reports whether the user had requested deletion
+func (m *DeletionModel) Cancel(ctx context.Context, userID int) (bool, error) {
+	sql := "DELETE FROM deletion_ WHERE user_id_ = $1;"
+
+	tag, err := m.db.Exec(ctx, sql, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// Returns deletions past their purge time, oldest first
+func (m *DeletionModel) Due(ctx context.Context, limit int) ([]*Deletion, error) {
+	sql := `SELECT
+			d.user_id_,
+			u.name_,
+			u.email_,
+			d.requested_at_,
+			d.purge_at_
+		FROM deletion_ d
+		INNER JOIN user_ u
+			ON u.id_ = d.user_id_
+		WHERE d.purge_at_ <= NOW()
+		ORDER BY d.purge_at_
+		LIMIT $1;`
+
+	rows, err := m.db.Query(ctx, sql, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanDeletion)
+}
+
+// Deletes the user and all of their data if their deletion is still due.
+// Returns ErrNoRecord if it was cancelled by logging in.
+func (m *DeletionModel) Purge(ctx context.Context, userID int) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := "DELETE FROM deletion_ WHERE user_id_ = $1 AND purge_at_ <= NOW();"
+
+	tag, err := tx.Exec(ctx, sql, userID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRecord
+	}
+
+	err = deleteUser(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	EmailPending = "pending"
+	EmailSent    = "sent"
+	EmailDead    = "dead"
+)
+
+type EmailModelInterface interface {
+	Insert(ctx context.Context, recipient, template, subject, body, htmlBody string) (int, error)
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*Email, error)
+	MarkSent(ctx context.Context, id int, messageID string) error
+	MarkFailed(ctx context.Context, id int, lastError string, nextAttempt *time.Time) error
+	Recent(ctx context.Context, status string, limit int) ([]*Email, error)
+	Counts(ctx context.Context) ([]*EmailStatusCount, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Outbox of rendered emails waiting to be delivered
+type EmailModel struct {
+	db dbtx
+}
+
+type Email struct {
+	ID            int
+	Recipient     string
+	Template      string
+	Subject       string
+	Body          string
+	HTMLBody      *string
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     *string
+	MessageID     *string
+	SentAt        *time.Time
+	CreatedAt     time.Time
+}
+
+func scanEmail(row pgx.CollectableRow) (*Email, error) {
+	var e Email
+	err := row.Scan(
+		&e.ID,
+		&e.Recipient,
+		&e.Template,
+		&e.Subject,
+		&e.Body,
+		&e.HTMLBody,
+		&e.Status,
+		&e.Attempts,
+		&e.NextAttemptAt,
+		&e.LastError,
+		&e.MessageID,
+		&e.SentAt,
+		&e.CreatedAt)
+
+	return &e, err
+}
+
+// Queues an email. An empty htmlBody sends a plain text email.
+func (m *EmailModel) Insert(ctx context.Context, recipient, template, subject, body, htmlBody string) (int, error) {
+	var id int
+
+	sql := `INSERT INTO email_
+		(recipient_, template_, subject_, body_, html_body_)
+		VALUES($1, $2, $3, $4, NULLIF($5, '')) RETURNING id_;`
+
+	err := m.db.QueryRow(ctx, sql,
+		recipient, template, subject, body, htmlBody).Scan(&id)
+
+	return id, err
+}
+
+// Claims up to limit pending emails that are due for delivery. Claimed emails
+// are leased by pushing back their next attempt, so that concurrent workers
+// do not send them twice.
+func (m *EmailModel) Claim(ctx context.Context, limit int, lease time.Duration) ([]*Email, error) {
+	sql := `UPDATE email_ SET next_attempt_at_ = $2
+		WHERE id_ IN (
+			SELECT id_ FROM email_
+			WHERE status_ = 'pending' AND next_attempt_at_ <= NOW()
+			ORDER BY next_attempt_at_
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *;`
+
+	rows, err := m.db.Query(ctx, sql, limit, time.Now().Add(lease))
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanEmail)
+}
+
+// Records a delivered email. The bodies are cleared since they may hold
+// tokens that should only live in the recipient's inbox.
+func (m *EmailModel) MarkSent(ctx context.Context, id int, messageID string) error {
+	sql := `UPDATE email_ SET
+			status_ = 'sent',
+			attempts_ = attempts_ + 1,
+			message_id_ = $2,
+			last_error_ = NULL,
+			sent_at_ = NOW(),
+			body_ = '',
+			html_body_ = NULL
+		WHERE id_ = $1;`
+
+	_, err := m.db.Exec(ctx, sql, id, messageID)
+
+	return err
+}
+
+// Records a failed delivery attempt. The email is retried at nextAttempt, or
+// dead-lettered with its bodies cleared if nextAttempt is nil.
+func (m *EmailModel) MarkFailed(ctx context.Context, id int, lastError string, nextAttempt *time.Time) error {
+	sql := `UPDATE email_ SET
+			status_ = CASE WHEN $3::TIMESTAMPTZ IS NULL THEN 'dead' ELSE 'pending' END,
+			attempts_ = attempts_ + 1,
+			last_error_ = $2,
+			next_attempt_at_ = COALESCE($3, next_attempt_at_),
+			body_ = CASE WHEN $3::TIMESTAMPTZ IS NULL THEN '' ELSE body_ END,
+			html_body_ = CASE WHEN $3::TIMESTAMPTZ IS NULL THEN NULL ELSE html_body_ END
+		WHERE id_ = $1;`
+
+	_, err := m.db.Exec(ctx, sql, id, lastError, nextAttempt)
+
+	return err
+}
+
+// Returns the most recent emails with the given status, newest first
+func (m *EmailModel) Recent(ctx context.Context, status string, limit int) ([]*Email, error) {
+	sql := `SELECT * FROM email_
+		WHERE status_ = $1
+		ORDER BY created_at_ DESC
+		LIMIT $2;`
+
+	rows, err := m.db.Query(ctx, sql, status, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanEmail)
+}
+
+type EmailStatusCount struct {
+	Status string
+	Count  int
+}
+
+func scanEmailStatusCount(row pgx.CollectableRow) (*EmailStatusCount, error) {
+	var c EmailStatusCount
+	err := row.Scan(&c.Status, &c.Count)
+
+	return &c, err
+}
+
+func (m *EmailModel) Counts(ctx context.Context) ([]*EmailStatusCount, error) {
+	sql := `SELECT status_, COUNT(*) FROM email_
+		GROUP BY status_
+		ORDER BY status_;`
+
+	rows, err := m.db.Query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanEmailStatusCount)
+}
+
+// Deletes sent and dead-lettered emails created before the given time and
+// returns how many were removed. Bodies still held by finished emails, such
+// as those sent before bodies were cleared on delivery, are cleared too.
+func (m *EmailModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	sql := `UPDATE email_ SET body_ = '', html_body_ = NULL
+		WHERE status_ IN ('sent', 'dead')
+		AND (body_ <> '' OR html_body_ IS NOT NULL);`
+
+	_, err := m.db.Exec(ctx, sql)
+	if err != nil {
+		return 0, err
+	}
+
+	sql = `DELETE FROM email_
+		WHERE status_ IN ('sent', 'dead') AND created_at_ < $1;`
+
+	tag, err := m.db.Exec(ctx, sql, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
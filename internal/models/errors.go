@@ -11,6 +11,14 @@ var (
 	ErrInvalidCredentials  = errors.New("models: invalid credentials")
 	ErrDuplicateEmail      = errors.New("models: duplicate email")
 	ErrExpiredVerification = errors.New("models: expired verification")
+	ErrPostClosed          = errors.New("models: post closed")
+	ErrDuplicateMember     = errors.New("models: duplicate member")
+	ErrInvalidChallenge    = errors.New("models: invalid challenge")
+	ErrActiveChallenge     = errors.New("models: active challenge")
+	ErrTournamentStarted   = errors.New("models: tournament started")
+	ErrNotEnoughPlayers    = errors.New("models: not enough players")
+	ErrTimeout             = errors.New("models: query timed out")
+	ErrRehash              = errors.New("models: password rehash failed")
 )
 
 func pgErrCode(err error) string {
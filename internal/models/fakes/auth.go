@@ -0,0 +1,522 @@
+package fakes
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/crypto"
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+type VerificationModel struct {
+	s *store
+}
+
+func (m *VerificationModel) Insert(ctx context.Context, token, purpose, email string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	m.s.verifications = append(m.s.verifications, &models.Verification{
+		TokenHash: crypto.HashToken(token),
+		Purpose:   purpose,
+		Email:     email,
+		Expiry:    time.Now().Add(24 * time.Hour),
+		CreatedAt: time.Now(),
+	})
+
+	return nil
+}
+
+func (m *VerificationModel) Latest(ctx context.Context, purpose, email string) (*models.Verification, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var latest *models.Verification
+	for _, v := range m.s.verifications {
+		if v.Purpose == purpose && v.Email == email {
+			latest = v
+		}
+	}
+
+	if latest == nil {
+		return nil, models.ErrNoRecord
+	}
+
+	return latest, nil
+}
+
+func (m *VerificationModel) Consume(ctx context.Context, token, purpose, email string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	hash := crypto.HashToken(token)
+
+	var found *models.Verification
+	for _, v := range m.s.verifications {
+		if v.TokenHash == hash && v.Purpose == purpose && v.Email == email && v.ConsumedAt == nil {
+			found = v
+			break
+		}
+	}
+
+	if found == nil {
+		return models.ErrNoRecord
+	}
+
+	now := time.Now()
+	found.ConsumedAt = &now
+
+	if found.IsExpired() {
+		return models.ErrExpiredVerification
+	}
+
+	for _, v := range m.s.verifications {
+		if v.Purpose == purpose && v.Email == email && v.ConsumedAt == nil {
+			v.ConsumedAt = &now
+		}
+	}
+
+	return nil
+}
+
+func (m *VerificationModel) Purge(ctx context.Context) (int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	n := len(m.s.verifications)
+	m.s.verifications = filter(m.s.verifications, func(v *models.Verification) bool {
+		return !v.IsExpired()
+	})
+
+	return int64(n - len(m.s.verifications)), nil
+}
+
+type AuthAttemptModel struct {
+	s *store
+}
+
+type authAttempt struct {
+	action    string
+	ip        string
+	email     string
+	failed    bool
+	createdAt time.Time
+}
+
+func (m *AuthAttemptModel) Insert(ctx context.Context, action, ip, email string, failed bool) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	m.s.attempts = append(m.s.attempts, &authAttempt{
+		action:    action,
+		ip:        ip,
+		email:     email,
+		failed:    failed,
+		createdAt: time.Now(),
+	})
+
+	return nil
+}
+
+func (m *AuthAttemptModel) Count(ctx context.Context, action, ip, email string, since time.Time) (int, int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var byIP, byEmail int
+	for _, a := range m.s.attempts {
+		if a.action != action || !a.createdAt.After(since) {
+			continue
+		}
+
+		if a.ip == ip {
+			byIP++
+		}
+
+		if a.email == email {
+			byEmail++
+		}
+	}
+
+	return byIP, byEmail, nil
+}
+
+func (m *AuthAttemptModel) Failures(ctx context.Context, email string, since time.Time) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for _, a := range m.s.attempts {
+		if a.action == "login" && a.email == email && !a.failed && a.createdAt.After(since) {
+			since = a.createdAt
+		}
+	}
+
+	n := 0
+	for _, a := range m.s.attempts {
+		if a.action == "login" && a.email == email && a.failed && a.createdAt.After(since) {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+type LockoutModel struct {
+	s *store
+}
+
+func (m *LockoutModel) Insert(ctx context.Context, email string, until time.Time) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	m.s.lockouts[email] = &models.Lockout{
+		Email:     email,
+		Until:     until,
+		CreatedAt: time.Now(),
+	}
+
+	return nil
+}
+
+func (m *LockoutModel) Get(ctx context.Context, email string) (*models.Lockout, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	l, ok := m.s.lockouts[email]
+	if !ok {
+		return nil, models.ErrNoRecord
+	}
+
+	return l, nil
+}
+
+func (m *LockoutModel) Delete(ctx context.Context, email string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	delete(m.s.lockouts, email)
+
+	return nil
+}
+
+type TOTPModel struct {
+	s *store
+}
+
+type recoveryCode struct {
+	userID int
+	hash   string
+	used   bool
+}
+
+func (m *TOTPModel) Enable(ctx context.Context, userID int, secret string, step int64, recoveryCodeHashes []string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if m.s.user(userID) == nil {
+		return ErrForeignKey
+	}
+
+	m.s.totps[userID] = &models.TOTP{
+		UserID:    userID,
+		Secret:    secret,
+		LastStep:  step,
+		CreatedAt: time.Now(),
+	}
+
+	m.s.recoveryCodes = filter(m.s.recoveryCodes, func(c *recoveryCode) bool { return c.userID != userID })
+	for _, h := range recoveryCodeHashes {
+		m.s.recoveryCodes = append(m.s.recoveryCodes, &recoveryCode{userID: userID, hash: h})
+	}
+
+	return nil
+}
+
+func (m *TOTPModel) Exists(ctx context.Context, userID int) (bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	_, ok := m.s.totps[userID]
+
+	return ok, nil
+}
+
+func (m *TOTPModel) Get(ctx context.Context, userID int) (*models.TOTP, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	t, ok := m.s.totps[userID]
+	if !ok {
+		return nil, models.ErrNoRecord
+	}
+
+	return t, nil
+}
+
+func (m *TOTPModel) UseStep(ctx context.Context, userID int, step int64) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	t, ok := m.s.totps[userID]
+	if !ok || t.LastStep >= step {
+		return models.ErrNoRecord
+	}
+
+	t.LastStep = step
+
+	return nil
+}
+
+func (m *TOTPModel) UseRecoveryCode(ctx context.Context, userID int, hash string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for _, c := range m.s.recoveryCodes {
+		if c.userID == userID && c.hash == hash && !c.used {
+			c.used = true
+
+			return nil
+		}
+	}
+
+	return models.ErrNoRecord
+}
+
+func (m *TOTPModel) RecoveryCodes(ctx context.Context, userID int) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	n := 0
+	for _, c := range m.s.recoveryCodes {
+		if c.userID == userID && !c.used {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+func (m *TOTPModel) Disable(ctx context.Context, userID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	m.s.recoveryCodes = filter(m.s.recoveryCodes, func(c *recoveryCode) bool { return c.userID != userID })
+	delete(m.s.totps, userID)
+
+	return nil
+}
+
+type LoginTokenModel struct {
+	s *store
+}
+
+type loginToken struct {
+	userID int
+	expiry time.Time
+}
+
+func (m *LoginTokenModel) Insert(ctx context.Context, tokenHash string, userID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if m.s.user(userID) == nil {
+		return ErrForeignKey
+	}
+
+	m.s.loginTokens[tokenHash] = &loginToken{
+		userID: userID,
+		expiry: time.Now().Add(15 * time.Minute),
+	}
+
+	return nil
+}
+
+func (m *LoginTokenModel) Consume(ctx context.Context, tokenHash string) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	t, ok := m.s.loginTokens[tokenHash]
+	if !ok {
+		return 0, models.ErrNoRecord
+	}
+
+	delete(m.s.loginTokens, tokenHash)
+
+	if time.Now().After(t.expiry) {
+		return 0, models.ErrExpiredVerification
+	}
+
+	return t.userID, nil
+}
+
+type IdentityModel struct {
+	s *store
+}
+
+type identityKey struct {
+	issuer  string
+	subject string
+}
+
+type identity struct {
+	userID    int
+	createdAt time.Time
+}
+
+func (m *IdentityModel) Insert(ctx context.Context, issuer, subject string, userID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if m.s.user(userID) == nil {
+		return ErrForeignKey
+	}
+
+	key := identityKey{issuer, subject}
+	if _, ok := m.s.identities[key]; !ok {
+		m.s.identities[key] = &identity{userID: userID, createdAt: time.Now()}
+	}
+
+	return nil
+}
+
+func (m *IdentityModel) GetUserID(ctx context.Context, issuer, subject string) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	i, ok := m.s.identities[identityKey{issuer, subject}]
+	if !ok {
+		return 0, models.ErrNoRecord
+	}
+
+	return i.userID, nil
+}
+
+func (m *IdentityModel) User(ctx context.Context, userID int) ([]*models.Identity, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var identities []*models.Identity
+	for key, i := range m.s.identities {
+		if i.userID == userID {
+			identities = append(identities, &models.Identity{
+				Issuer:    key.issuer,
+				Subject:   key.subject,
+				CreatedAt: i.createdAt,
+			})
+		}
+	}
+
+	sort.Slice(identities, func(a, b int) bool {
+		return identities[a].CreatedAt.Before(identities[b].CreatedAt)
+	})
+
+	return identities, nil
+}
+
+// Session metadata only. Unlike the real model, sessions are not joined with
+// the session manager's store, so none of them expire.
+type SessionModel struct {
+	s *store
+}
+
+type session struct {
+	userID     int
+	ip         string
+	userAgent  string
+	createdAt  time.Time
+	lastSeenAt time.Time
+}
+
+func (m *SessionModel) Insert(ctx context.Context, token string, userID int, ip, userAgent string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if m.s.user(userID) == nil {
+		return ErrForeignKey
+	}
+
+	if _, ok := m.s.sessions[token]; !ok {
+		m.s.sessions[token] = &session{
+			userID:     userID,
+			ip:         ip,
+			userAgent:  userAgent,
+			createdAt:  time.Now(),
+			lastSeenAt: time.Now(),
+		}
+	}
+
+	return nil
+}
+
+func (m *SessionModel) Touch(ctx context.Context, token, ip string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if s, ok := m.s.sessions[token]; ok {
+		s.lastSeenAt = time.Now()
+		s.ip = ip
+	}
+
+	return nil
+}
+
+func (m *SessionModel) User(ctx context.Context, userID int, currentToken string) ([]*models.Session, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var sessions []*models.Session
+	for token, s := range m.s.sessions {
+		if s.userID != userID {
+			continue
+		}
+
+		sessions = append(sessions, &models.Session{
+			IP:         s.ip,
+			UserAgent:  s.userAgent,
+			IsCurrent:  token == currentToken,
+			CreatedAt:  s.createdAt,
+			LastSeenAt: s.lastSeenAt,
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].IsCurrent != sessions[j].IsCurrent {
+			return sessions[i].IsCurrent
+		}
+
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	return sessions, nil
+}
+
+func (m *SessionModel) Delete(ctx context.Context, token string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	delete(m.s.sessions, token)
+
+	return nil
+}
+
+// Only removes the metadata. Tests using the session manager's memory store
+// must destroy its sessions themselves.
+func (m *SessionModel) DeleteOthers(ctx context.Context, userID int, token string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for t, s := range m.s.sessions {
+		if s.userID == userID && t != token {
+			delete(m.s.sessions, t)
+		}
+	}
+
+	return nil
+}
+
+func (m *SessionModel) Active(ctx context.Context) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	return len(m.s.sessions), nil
+}
@@ -0,0 +1,95 @@
+package fakes
+
+import (
+	"context"
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+type ContactModel struct {
+	s *store
+}
+
+type contact struct {
+	id       int
+	value    string
+	userID   int
+	methodID int
+}
+
+func (m *ContactModel) Insert(ctx context.Context, value string, userID, methodID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if m.s.user(userID) == nil || !valid(methodID, len(contactMethods)) {
+		return ErrForeignKey
+	}
+
+	m.s.contacts = append(m.s.contacts, &contact{
+		id:       m.s.id(),
+		value:    value,
+		userID:   userID,
+		methodID: methodID,
+	})
+
+	return nil
+}
+
+func (m *ContactModel) Exists(ctx context.Context, userID int) (bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for _, c := range m.s.contacts {
+		if c.userID == userID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (m *ContactModel) Methods(ctx context.Context) ([]*models.ContactMethod, error) {
+	return contactMethods, nil
+}
+
+func (m *ContactModel) UserContacts(ctx context.Context, userID int) ([]*models.UserContact, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var contacts []*models.UserContact
+	for _, c := range m.s.contacts {
+		if c.userID != userID {
+			continue
+		}
+
+		for _, method := range contactMethods {
+			if method.ID == c.methodID {
+				contacts = append(contacts, &models.UserContact{
+					ID:     c.id,
+					Value:  c.value,
+					Method: method.Name,
+				})
+			}
+		}
+	}
+
+	return contacts, nil
+}
+
+func (m *ContactModel) MethodID(ctx context.Context, name string) (int, error) {
+	for _, method := range contactMethods {
+		if method.Name == name {
+			return method.ID, nil
+		}
+	}
+
+	return 0, models.ErrNoRecord
+}
+
+func (m *ContactModel) Delete(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	m.s.contacts = filter(m.s.contacts, func(c *contact) bool { return c.id != id })
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package fakes
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+type DeletionModel struct {
+	s *store
+}
+
+type deletion struct {
+	requestedAt time.Time
+	purgeAt     time.Time
+}
+
+func (s *store) deletion(userID int) *models.Deletion {
+	d, ok := s.deletions[userID]
+	if !ok {
+		return nil
+	}
+
+	u := s.user(userID)
+
+	return &models.Deletion{
+		UserID:      userID,
+		Name:        u.Name,
+		Email:       u.Email,
+		RequestedAt: d.requestedAt,
+		PurgeAt:     d.purgeAt,
+	}
+}
+
+func (m *DeletionModel) Request(ctx context.Context, userID int, purgeAt time.Time) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if m.s.user(userID) == nil {
+		return ErrForeignKey
+	}
+
+	if _, ok := m.s.deletions[userID]; !ok {
+		m.s.deletions[userID] = &deletion{
+			requestedAt: time.Now(),
+			purgeAt:     purgeAt,
+		}
+	}
+
+	return nil
+}
+
+func (m *DeletionModel) Get(ctx context.Context, userID int) (*models.Deletion, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	d := m.s.deletion(userID)
+	if d == nil {
+		return nil, models.ErrNoRecord
+	}
+
+	return d, nil
+}
+
+func (m *DeletionModel) Cancel(ctx context.Context, userID int) (bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	_, ok := m.s.deletions[userID]
+	delete(m.s.deletions, userID)
+
+	return ok, nil
+}
+
+func (m *DeletionModel) Due(ctx context.Context, limit int) ([]*models.Deletion, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var due []*models.Deletion
+	for userID, d := range m.s.deletions {
+		if !d.purgeAt.After(time.Now()) {
+			due = append(due, m.s.deletion(userID))
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].PurgeAt.Before(due[j].PurgeAt)
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	return due, nil
+}
+
+func (m *DeletionModel) Purge(ctx context.Context, userID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	d, ok := m.s.deletions[userID]
+	if !ok || d.purgeAt.After(time.Now()) {
+		return models.ErrNoRecord
+	}
+
+	return m.s.deleteUser(userID)
+}
+
+// Moves the purge time of the user's deletion into the past, as if the grace
+// period had passed
+func ExpireDeletion(m models.Models, userID int) {
+	d := m.Deletion.(*DeletionModel)
+
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+
+	if x, ok := d.s.deletions[userID]; ok {
+		x.purgeAt = time.Now().Add(-time.Second)
+	}
+}
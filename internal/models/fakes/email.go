@@ -0,0 +1,177 @@
+package fakes
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+type EmailModel struct {
+	s *store
+}
+
+func (m *EmailModel) Insert(ctx context.Context, recipient, template, subject, body, htmlBody string) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	e := &models.Email{
+		ID:            m.s.id(),
+		Recipient:     recipient,
+		Template:      template,
+		Subject:       subject,
+		Body:          body,
+		Status:        models.EmailPending,
+		NextAttemptAt: time.Now(),
+		CreatedAt:     time.Now(),
+	}
+
+	if htmlBody != "" {
+		e.HTMLBody = &htmlBody
+	}
+
+	m.s.emails = append(m.s.emails, e)
+
+	return e.ID, nil
+}
+
+func (s *store) email(id int) *models.Email {
+	for _, e := range s.emails {
+		if e.ID == id {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// Returns copies of the rows, as a query would
+func (m *EmailModel) Claim(ctx context.Context, limit int, lease time.Duration) ([]*models.Email, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var due []*models.Email
+	for _, e := range m.s.emails {
+		if e.Status == models.EmailPending && !e.NextAttemptAt.After(time.Now()) {
+			due = append(due, e)
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	var claimed []*models.Email
+	for _, e := range due {
+		e.NextAttemptAt = time.Now().Add(lease)
+
+		c := *e
+		claimed = append(claimed, &c)
+	}
+
+	return claimed, nil
+}
+
+func (m *EmailModel) MarkSent(ctx context.Context, id int, messageID string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if e := m.s.email(id); e != nil {
+		now := time.Now()
+
+		e.Status = models.EmailSent
+		e.Attempts++
+		e.MessageID = &messageID
+		e.LastError = nil
+		e.SentAt = &now
+		e.Body = ""
+		e.HTMLBody = nil
+	}
+
+	return nil
+}
+
+func (m *EmailModel) MarkFailed(ctx context.Context, id int, lastError string, nextAttempt *time.Time) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if e := m.s.email(id); e != nil {
+		e.Attempts++
+		e.LastError = &lastError
+
+		if nextAttempt == nil {
+			e.Status = models.EmailDead
+			e.Body = ""
+			e.HTMLBody = nil
+		} else {
+			e.Status = models.EmailPending
+			e.NextAttemptAt = *nextAttempt
+		}
+	}
+
+	return nil
+}
+
+func (m *EmailModel) Recent(ctx context.Context, status string, limit int) ([]*models.Email, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var emails []*models.Email
+	for i := len(m.s.emails) - 1; i >= 0 && len(emails) < limit; i-- {
+		if e := m.s.emails[i]; e.Status == status {
+			c := *e
+			emails = append(emails, &c)
+		}
+	}
+
+	return emails, nil
+}
+
+func (m *EmailModel) Counts(ctx context.Context) ([]*models.EmailStatusCount, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var counts []*models.EmailStatusCount
+	for _, status := range []string{models.EmailDead, models.EmailPending, models.EmailSent} {
+		c := &models.EmailStatusCount{Status: status}
+		for _, e := range m.s.emails {
+			if e.Status == status {
+				c.Count++
+			}
+		}
+
+		if c.Count > 0 {
+			counts = append(counts, c)
+		}
+	}
+
+	return counts, nil
+}
+
+func (m *EmailModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	n := len(m.s.emails)
+	m.s.emails = filter(m.s.emails, func(e *models.Email) bool {
+		return e.Status == models.EmailPending || !e.CreatedAt.Before(before)
+	})
+
+	return int64(n - len(m.s.emails)), nil
+}
+
+type AdminModel struct {
+	s *store
+}
+
+func (m *AdminModel) Exists(ctx context.Context, userID int) (bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	return m.s.admins[userID], nil
+}
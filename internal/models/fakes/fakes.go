@@ -0,0 +1,202 @@
+// Package fakes provides in-memory implementations of the model interfaces so
+// that handlers can be tested without a database. The models share a single
+// store, so lookups across tables behave as they do with the real schema.
+package fakes
+
+import (
+	"errors"
+	"maps"
+	"sync"
+
+	"github.com/micahco/racket-connections/internal/crypto"
+	"github.com/micahco/racket-connections/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Returned where the database would raise a foreign key violation
+var ErrForeignKey = errors.New("fakes: foreign key violation")
+
+// Reference data inserted by sql/init.sql
+var (
+	days = []*models.DayOfWeek{
+		{ID: 1, Name: "monday", Abbrev: "mon"},
+		{ID: 2, Name: "tuesday", Abbrev: "tue"},
+		{ID: 3, Name: "wednesday", Abbrev: "wed"},
+		{ID: 4, Name: "thursday", Abbrev: "thu"},
+		{ID: 5, Name: "friday", Abbrev: "fri"},
+		{ID: 6, Name: "saturday", Abbrev: "sat"},
+		{ID: 7, Name: "sunday", Abbrev: "sun"},
+	}
+
+	times = []*models.TimeOfDay{
+		{ID: 1, Name: "morning", Abbrev: "mor"},
+		{ID: 2, Name: "afternoon", Abbrev: "aft"},
+		{ID: 3, Name: "evening", Abbrev: "eve"},
+	}
+
+	contactMethods = []*models.ContactMethod{
+		{ID: 1, Name: "email"},
+		{ID: 2, Name: "phone"},
+		{ID: 3, Name: "other"},
+	}
+
+	sports = []*models.Sport{
+		{ID: 1, Name: "tennis"},
+		{ID: 2, Name: "badminton"},
+		{ID: 3, Name: "table tennis"},
+		{ID: 4, Name: "pickleball"},
+		{ID: 5, Name: "racquetball"},
+		{ID: 6, Name: "squash"},
+	}
+
+	postFormats = []*models.PostFormat{
+		{ID: 1, Name: "singles", MaxPlayersNeeded: 1},
+		{ID: 2, Name: "doubles", MaxPlayersNeeded: 3},
+		{ID: 3, Name: "group", MaxPlayersNeeded: 10},
+	}
+
+	skillLevels = []*models.SkillLevel{
+		{ID: 1, Name: "beginner"},
+		{ID: 2, Name: "novice"},
+		{ID: 3, Name: "intermediate"},
+		{ID: 4, Name: "advanced"},
+		{ID: 5, Name: "expert"},
+	}
+)
+
+type store struct {
+	mu sync.Mutex
+	tables
+}
+
+type tables struct {
+	nextID int
+
+	users         []*models.User
+	contacts      []*contact
+	timeslots     []*timeslot
+	posts         []*models.Post
+	roster        []*rosterEntry
+	matches       []*match
+	verifications []*models.Verification
+	attempts      []*authAttempt
+	lockouts      map[string]*models.Lockout
+	sessions      map[string]*session
+	totps         map[int]*models.TOTP
+	recoveryCodes []*recoveryCode
+	loginTokens   map[string]*loginToken
+	identities    map[identityKey]*identity
+	emails        []*models.Email
+	admins        map[int]bool
+	deletions     map[int]*deletion
+}
+
+// Returns a copy of the tables that later writes to the store do not change.
+// Rows are copied since the models update them in place.
+func (t tables) clone() tables {
+	c := t
+	c.users = cloneRows(t.users)
+	c.contacts = cloneRows(t.contacts)
+	c.timeslots = cloneRows(t.timeslots)
+	c.posts = cloneRows(t.posts)
+	c.roster = cloneRows(t.roster)
+	c.matches = cloneRows(t.matches)
+	c.verifications = cloneRows(t.verifications)
+	c.attempts = cloneRows(t.attempts)
+	c.lockouts = cloneRowMap(t.lockouts)
+	c.sessions = cloneRowMap(t.sessions)
+	c.totps = cloneRowMap(t.totps)
+	c.recoveryCodes = cloneRows(t.recoveryCodes)
+	c.loginTokens = cloneRowMap(t.loginTokens)
+	c.identities = cloneRowMap(t.identities)
+	c.emails = cloneRows(t.emails)
+	c.admins = maps.Clone(t.admins)
+	c.deletions = cloneRowMap(t.deletions)
+
+	return c
+}
+
+func cloneRows[T any](rows []*T) []*T {
+	c := make([]*T, len(rows))
+	for i, r := range rows {
+		row := *r
+		c[i] = &row
+	}
+
+	return c
+}
+
+func cloneRowMap[K comparable, V any](rows map[K]*V) map[K]*V {
+	c := make(map[K]*V, len(rows))
+	for k, r := range rows {
+		row := *r
+		c[k] = &row
+	}
+
+	return c
+}
+
+// Reports whether id is the ID of one of n rows of reference data
+func valid(id, n int) bool {
+	return id >= 1 && id <= n
+}
+
+// Returns the next value of a sequence shared by every table
+func (s *store) id() int {
+	s.nextID++
+
+	return s.nextID
+}
+
+// Returns models backed by a new, empty store. There are no fakes for the
+// ladder and tournament models, which are left nil.
+func New() models.Models {
+	s := &store{tables: tables{
+		lockouts:    make(map[string]*models.Lockout),
+		sessions:    make(map[string]*session),
+		totps:       make(map[int]*models.TOTP),
+		loginTokens: make(map[string]*loginToken),
+		identities:  make(map[identityKey]*identity),
+		admins:      make(map[int]bool),
+		deletions:   make(map[int]*deletion),
+	}}
+
+	tx := &TxModel{s: s}
+
+	m := models.Models{
+		Post:         &PostModel{s},
+		PostFormat:   &PostFormatModel{s},
+		Roster:       &RosterModel{s},
+		Match:        &MatchModel{s},
+		Skill:        &SkillLevelModel{s},
+		Sport:        &SportModel{s},
+		User:         &UserModel{s, &crypto.BcryptHasher{Cost: bcrypt.MinCost}},
+		Contact:      &ContactModel{s},
+		Timeslot:     &TimeslotModel{s},
+		Verification: &VerificationModel{s},
+		AuthAttempt:  &AuthAttemptModel{s},
+		Lockout:      &LockoutModel{s},
+		TOTP:         &TOTPModel{s},
+		LoginToken:   &LoginTokenModel{s},
+		Identity:     &IdentityModel{s},
+		Session:      &SessionModel{s},
+		Email:        &EmailModel{s},
+		Admin:        &AdminModel{s},
+		Deletion:     &DeletionModel{s},
+		Tx:           tx,
+	}
+	tx.models = m
+
+	return m
+}
+
+// Grants the user access to the admin pages. There is no model method for
+// this since admins are granted with SQL.
+func GrantAdmin(m models.Models, userID int) {
+	a := m.Admin.(*AdminModel)
+
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+
+	a.s.admins[userID] = true
+}
@@ -0,0 +1,181 @@
+package fakes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+type MatchModel struct {
+	s *store
+}
+
+// A zero player ID stands in for the NULL left by a deleted account
+type match struct {
+	id          int
+	sportID     int
+	reporterID  int
+	opponentID  int
+	winnerID    int
+	playedOn    time.Time
+	sets        []models.MatchSet
+	confirmedAt *time.Time
+}
+
+func (m *MatchModel) Insert(ctx context.Context, sportID, reporterID, opponentID, winnerID int, playedOn time.Time, sets []models.MatchSet) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if m.s.user(reporterID) == nil || m.s.user(opponentID) == nil || !valid(sportID, len(sports)) {
+		return 0, ErrForeignKey
+	}
+
+	x := &match{
+		id:         m.s.id(),
+		sportID:    sportID,
+		reporterID: reporterID,
+		opponentID: opponentID,
+		winnerID:   winnerID,
+		playedOn:   playedOn,
+		sets:       sets,
+	}
+	m.s.matches = append(m.s.matches, x)
+
+	return x.id, nil
+}
+
+func (m *MatchModel) Confirm(ctx context.Context, id, opponentID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for _, x := range m.s.matches {
+		if x.id == id && x.opponentID == opponentID && x.confirmedAt == nil {
+			now := time.Now()
+			x.confirmedAt = &now
+
+			return nil
+		}
+	}
+
+	return models.ErrNoRecord
+}
+
+func (m *MatchModel) Reject(ctx context.Context, id, userID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for i, x := range m.s.matches {
+		if x.id == id && (x.reporterID == userID || x.opponentID == userID) && x.confirmedAt == nil {
+			m.s.matches = append(m.s.matches[:i], m.s.matches[i+1:]...)
+
+			return nil
+		}
+	}
+
+	return models.ErrNoRecord
+}
+
+func (m *MatchModel) User(ctx context.Context, userID int) ([]*models.UserMatch, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var matches []*models.UserMatch
+	for _, x := range m.s.matches {
+		if x.reporterID != userID && x.opponentID != userID {
+			continue
+		}
+
+		isReporter := x.reporterID == userID
+
+		opponentID := x.reporterID
+		if isReporter {
+			opponentID = x.opponentID
+		}
+
+		var score []string
+		for _, set := range x.sets {
+			if isReporter {
+				score = append(score, fmt.Sprintf("%d-%d", set.PlayerScore, set.OpponentScore))
+			} else {
+				score = append(score, fmt.Sprintf("%d-%d", set.OpponentScore, set.PlayerScore))
+			}
+		}
+
+		um := &models.UserMatch{
+			ID:          x.id,
+			Sport:       sports[x.sportID-1].Name,
+			OpponentID:  opponentID,
+			Score:       strings.Join(score, " "),
+			Won:         x.winnerID == userID,
+			IsReporter:  isReporter,
+			IsConfirmed: x.confirmedAt != nil,
+			PlayedOn:    x.playedOn,
+		}
+
+		um.OpponentName = m.s.userName(opponentID)
+
+		matches = append(matches, um)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if !matches[i].PlayedOn.Equal(matches[j].PlayedOn) {
+			return matches[i].PlayedOn.After(matches[j].PlayedOn)
+		}
+
+		return matches[i].ID > matches[j].ID
+	})
+
+	return matches, nil
+}
+
+func (m *MatchModel) HeadToHead(ctx context.Context, userID int) ([]*models.HeadToHead, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	records := make(map[int]*models.HeadToHead)
+	var h2h []*models.HeadToHead
+
+	for _, x := range m.s.matches {
+		if x.confirmedAt == nil || (x.reporterID != userID && x.opponentID != userID) {
+			continue
+		}
+
+		opponentID := x.reporterID
+		if x.reporterID == userID {
+			opponentID = x.opponentID
+		}
+
+		h, ok := records[opponentID]
+		if !ok {
+			h = &models.HeadToHead{
+				OpponentID:   opponentID,
+				OpponentName: m.s.userName(opponentID),
+			}
+
+			records[opponentID] = h
+			h2h = append(h2h, h)
+		}
+
+		if x.winnerID == userID {
+			h.Wins++
+		} else {
+			h.Losses++
+		}
+	}
+
+	sort.SliceStable(h2h, func(i, j int) bool {
+		ni := h2h[i].Wins + h2h[i].Losses
+		nj := h2h[j].Wins + h2h[j].Losses
+		if ni != nj {
+			return ni > nj
+		}
+
+		return h2h[i].OpponentName < h2h[j].OpponentName
+	})
+
+	return h2h, nil
+}
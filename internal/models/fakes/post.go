@@ -0,0 +1,406 @@
+package fakes
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+type PostModel struct {
+	s *store
+}
+
+func (m *PostModel) Insert(ctx context.Context, userID, sportID, skillLevelID, formatID, playersNeeded int, comment string) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if m.s.user(userID) == nil ||
+		!valid(sportID, len(sports)) ||
+		!valid(skillLevelID, len(skillLevels)) ||
+		!valid(formatID, len(postFormats)) {
+		return 0, ErrForeignKey
+	}
+
+	p := &models.Post{
+		ID:            m.s.id(),
+		Comment:       comment,
+		CreatedAt:     time.Now(),
+		UserID:        userID,
+		SportID:       sportID,
+		SkillLevelID:  skillLevelID,
+		FormatID:      formatID,
+		PlayersNeeded: playersNeeded,
+	}
+	m.s.posts = append(m.s.posts, p)
+
+	return p.ID, nil
+}
+
+func (s *store) post(id int) *models.Post {
+	for _, p := range s.posts {
+		if p.ID == id {
+			return p
+		}
+	}
+
+	return nil
+}
+
+func (m *PostModel) GetID(ctx context.Context, userID, sportID int) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for _, p := range m.s.posts {
+		if p.UserID == userID && p.SportID == sportID {
+			return p.ID, nil
+		}
+	}
+
+	return 0, models.ErrNoRecord
+}
+
+func (m *PostModel) GetUserID(ctx context.Context, id int) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	p := m.s.post(id)
+	if p == nil {
+		return 0, models.ErrNoRecord
+	}
+
+	return p.UserID, nil
+}
+
+func (m *PostModel) Delete(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	m.s.posts = filter(m.s.posts, func(p *models.Post) bool { return p.ID != id })
+	m.s.deleteRoster(id)
+
+	return nil
+}
+
+func (m *PostModel) Fetch(ctx context.Context, sportNames []string, timeslots []models.Timeslot) ([]*models.PostCard, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var cards []*models.PostCard
+	for _, p := range m.s.posts {
+		if p.ClosedAt != nil {
+			continue
+		}
+
+		sport := sports[p.SportID-1].Name
+		if len(sportNames) != 0 && !contains(sportNames, sport) {
+			continue
+		}
+
+		if len(timeslots) != 0 && !m.s.available(p.UserID, timeslots) {
+			continue
+		}
+
+		u := m.s.user(p.UserID)
+		if u == nil {
+			continue
+		}
+
+		cards = append(cards, &models.PostCard{
+			ID:         p.ID,
+			CreatedAt:  p.CreatedAt,
+			Sport:      sport,
+			UserName:   u.Name,
+			SkillLevel: skillLevels[p.SkillLevelID-1].Name,
+			Format:     postFormats[p.FormatID-1].Name,
+		})
+	}
+
+	sort.Slice(cards, func(i, j int) bool { return cards[i].ID > cards[j].ID })
+
+	return cards, nil
+}
+
+// Reports whether the user is available at any of the timeslots
+func (s *store) available(userID int, timeslots []models.Timeslot) bool {
+	for _, a := range s.userTimeslots(userID) {
+		for _, b := range timeslots {
+			if a.Day.Abbrev == b.Day.Abbrev && a.Time.Abbrev == b.Time.Abbrev {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func contains(xs []string, x string) bool {
+	for _, y := range xs {
+		if y == x {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *PostModel) User(ctx context.Context, userID int) ([]*models.ProfilePost, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var posts []*models.ProfilePost
+	for _, p := range m.s.posts {
+		if p.UserID != userID {
+			continue
+		}
+
+		posts = append(posts, &models.ProfilePost{
+			ID:            p.ID,
+			Comment:       p.Comment,
+			CreatedAt:     p.CreatedAt,
+			Sport:         sports[p.SportID-1].Name,
+			SkillLevel:    skillLevels[p.SkillLevelID-1].Name,
+			Format:        postFormats[p.FormatID-1].Name,
+			PlayersNeeded: p.PlayersNeeded,
+		})
+	}
+
+	return posts, nil
+}
+
+func (m *PostModel) GetDetails(ctx context.Context, id int) (*models.PostDetails, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	p := m.s.post(id)
+	if p == nil {
+		return nil, models.ErrNoRecord
+	}
+
+	u := m.s.user(p.UserID)
+	if u == nil {
+		return nil, models.ErrNoRecord
+	}
+
+	return &models.PostDetails{
+		ID:             p.ID,
+		Comment:        p.Comment,
+		CreatedAt:      p.CreatedAt,
+		UserID:         u.ID,
+		UserName:       u.Name,
+		SportID:        p.SportID,
+		Sport:          sports[p.SportID-1].Name,
+		SkillLevelID:   p.SkillLevelID,
+		SkillLevelName: skillLevels[p.SkillLevelID-1].Name,
+		Format:         postFormats[p.FormatID-1].Name,
+		PlayersNeeded:  p.PlayersNeeded,
+		ClosedAt:       p.ClosedAt,
+	}, nil
+}
+
+func (m *PostModel) CountBySport(ctx context.Context) ([]*models.SportCount, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var counts []*models.SportCount
+	for _, sport := range sports {
+		c := &models.SportCount{Sport: sport.Name}
+		for _, p := range m.s.posts {
+			if p.SportID == sport.ID && p.ClosedAt == nil {
+				c.Count++
+			}
+		}
+
+		counts = append(counts, c)
+	}
+
+	return counts, nil
+}
+
+type RosterModel struct {
+	s *store
+}
+
+type rosterEntry struct {
+	postID   int
+	userID   int
+	joinedAt time.Time
+}
+
+func (m *RosterModel) Insert(ctx context.Context, postID, userID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	p := m.s.post(postID)
+	if p == nil {
+		return models.ErrNoRecord
+	}
+
+	if p.ClosedAt != nil {
+		return models.ErrPostClosed
+	}
+
+	n := 0
+	for _, r := range m.s.roster {
+		if r.postID != postID {
+			continue
+		}
+
+		if r.userID == userID {
+			return models.ErrDuplicateMember
+		}
+
+		n++
+	}
+
+	m.s.roster = append(m.s.roster, &rosterEntry{
+		postID:   postID,
+		userID:   userID,
+		joinedAt: time.Now(),
+	})
+
+	if n+1 >= p.PlayersNeeded {
+		now := time.Now()
+		p.ClosedAt = &now
+	}
+
+	return nil
+}
+
+func (m *RosterModel) Delete(ctx context.Context, postID, userID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	n := len(m.s.roster)
+	m.s.roster = filter(m.s.roster, func(r *rosterEntry) bool {
+		return r.postID != postID || r.userID != userID
+	})
+
+	if len(m.s.roster) == n {
+		return models.ErrNoRecord
+	}
+
+	members := 0
+	for _, r := range m.s.roster {
+		if r.postID == postID {
+			members++
+		}
+	}
+
+	// Only reopen posts that were closed because the roster was full
+	if p := m.s.post(postID); p != nil && p.ClosedAt != nil && members+1 >= p.PlayersNeeded {
+		p.ClosedAt = nil
+	}
+
+	return nil
+}
+
+func (m *RosterModel) User(ctx context.Context, userID int) ([]*models.RosterPost, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var posts []*models.RosterPost
+	for _, r := range m.s.roster {
+		if r.userID != userID {
+			continue
+		}
+
+		p := m.s.post(r.postID)
+		if p == nil {
+			continue
+		}
+
+		rp := &models.RosterPost{
+			PostID:   p.ID,
+			Sport:    sports[p.SportID-1].Name,
+			JoinedAt: r.joinedAt,
+		}
+		if u := m.s.user(p.UserID); u != nil {
+			rp.PostedBy = u.Name
+		}
+
+		posts = append(posts, rp)
+	}
+
+	return posts, nil
+}
+
+func (s *store) deleteRoster(postID int) {
+	s.roster = filter(s.roster, func(r *rosterEntry) bool { return r.postID != postID })
+}
+
+func (m *RosterModel) Post(ctx context.Context, postID int) ([]*models.RosterMember, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var members []*models.RosterMember
+	for _, r := range m.s.roster {
+		if r.postID != postID {
+			continue
+		}
+
+		rm := &models.RosterMember{UserID: r.userID, JoinedAt: r.joinedAt}
+		if u := m.s.user(r.userID); u != nil {
+			rm.UserName = u.Name
+		}
+
+		members = append(members, rm)
+	}
+
+	return members, nil
+}
+
+type PostFormatModel struct {
+	s *store
+}
+
+func (m *PostFormatModel) All(ctx context.Context) ([]*models.PostFormat, error) {
+	return postFormats, nil
+}
+
+func (m *PostFormatModel) Get(ctx context.Context, id int) (*models.PostFormat, error) {
+	for _, f := range postFormats {
+		if f.ID == id {
+			return f, nil
+		}
+	}
+
+	return nil, models.ErrNoRecord
+}
+
+type SkillLevelModel struct {
+	s *store
+}
+
+func (m *SkillLevelModel) All(ctx context.Context) ([]*models.SkillLevel, error) {
+	return skillLevels, nil
+}
+
+type SportModel struct {
+	s *store
+}
+
+func (m *SportModel) All(ctx context.Context) ([]*models.Sport, error) {
+	return sports, nil
+}
+
+func (m *SportModel) GetID(ctx context.Context, name string) (int, error) {
+	for _, s := range sports {
+		if s.Name == name {
+			return s.ID, nil
+		}
+	}
+
+	return 0, models.ErrNoRecord
+}
+
+func (m *SportModel) Get(ctx context.Context, id int) (*models.Sport, error) {
+	for _, s := range sports {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+
+	return nil, models.ErrNoRecord
+}
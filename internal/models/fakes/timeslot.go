@@ -0,0 +1,73 @@
+package fakes
+
+import (
+	"context"
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+type TimeslotModel struct {
+	s *store
+}
+
+type timeslot struct {
+	userID int
+	dayID  int
+	timeID int
+}
+
+func (m *TimeslotModel) Days(ctx context.Context) ([]*models.DayOfWeek, error) {
+	return days, nil
+}
+
+func (m *TimeslotModel) Times(ctx context.Context) ([]*models.TimeOfDay, error) {
+	return times, nil
+}
+
+func (m *TimeslotModel) Insert(ctx context.Context, userID, dayID, timeID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if m.s.user(userID) == nil || !valid(dayID, len(days)) || !valid(timeID, len(times)) {
+		return ErrForeignKey
+	}
+
+	m.s.timeslots = append(m.s.timeslots, &timeslot{
+		userID: userID,
+		dayID:  dayID,
+		timeID: timeID,
+	})
+
+	return nil
+}
+
+func (s *store) userTimeslots(userID int) []*models.Timeslot {
+	var timeslots []*models.Timeslot
+	for _, t := range s.timeslots {
+		if t.userID != userID {
+			continue
+		}
+
+		timeslots = append(timeslots, &models.Timeslot{
+			Day:  days[t.dayID-1],
+			Time: times[t.timeID-1],
+		})
+	}
+
+	return timeslots
+}
+
+func (m *TimeslotModel) User(ctx context.Context, userID int) ([]*models.Timeslot, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	return m.s.userTimeslots(userID), nil
+}
+
+func (m *TimeslotModel) DeleteUser(ctx context.Context, userID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	m.s.timeslots = filter(m.s.timeslots, func(t *timeslot) bool { return t.userID != userID })
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package fakes
+
+import (
+	"context"
+
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+// Unlike a real transaction, writes made by fn are visible to other callers
+// before it returns. Tests that need isolation must not run requests
+// concurrently.
+type TxModel struct {
+	s      *store
+	models models.Models
+}
+
+// Runs fn with the same models, restoring the store as it was before fn if
+// it returns an error
+func (m *TxModel) Do(ctx context.Context, fn func(tx models.Models) error) error {
+	m.s.mu.Lock()
+	snapshot := m.s.tables.clone()
+	m.s.mu.Unlock()
+
+	err := fn(m.models)
+	if err != nil {
+		m.s.mu.Lock()
+		m.s.tables = snapshot
+		m.s.mu.Unlock()
+
+		return err
+	}
+
+	return nil
+}
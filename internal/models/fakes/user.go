@@ -0,0 +1,259 @@
+package fakes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/micahco/racket-connections/internal/crypto"
+	"github.com/micahco/racket-connections/internal/models"
+)
+
+type UserModel struct {
+	s      *store
+	hasher crypto.PasswordHasher
+}
+
+// Emails are case insensitive, as with the citext column
+func (s *store) userByEmail(email string) *models.User {
+	for _, u := range s.users {
+		if strings.EqualFold(u.Email, email) {
+			return u
+		}
+	}
+
+	return nil
+}
+
+func (s *store) user(id int) *models.User {
+	for _, u := range s.users {
+		if u.ID == id {
+			return u
+		}
+	}
+
+	return nil
+}
+
+// Matches the COALESCE the real queries use for deleted accounts
+func (s *store) userName(id int) string {
+	if u := s.user(id); u != nil {
+		return u.Name
+	}
+
+	return "Deleted user"
+}
+
+func (m *UserModel) Insert(ctx context.Context, name, email, password string) (int, error) {
+	hash, err := m.hasher.Hash(password)
+	if err != nil {
+		return 0, err
+	}
+
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if m.s.userByEmail(email) != nil {
+		return 0, models.ErrDuplicateEmail
+	}
+
+	u := &models.User{
+		ID:           m.s.id(),
+		Name:         name,
+		Email:        email,
+		PasswordHash: hash,
+		CreatedAt:    time.Now(),
+	}
+	m.s.users = append(m.s.users, u)
+
+	return u.ID, nil
+}
+
+func (m *UserModel) Authenticate(ctx context.Context, email, password string) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	u := m.s.userByEmail(email)
+	if u == nil {
+		return 0, models.ErrInvalidCredentials
+	}
+
+	err := crypto.ComparePassword(u.PasswordHash, password)
+	if err != nil {
+		if errors.Is(err, crypto.ErrMismatchedPassword) {
+			return 0, models.ErrInvalidCredentials
+		}
+
+		return 0, err
+	}
+
+	if m.hasher.NeedsRehash(u.PasswordHash) {
+		hash, err := m.hasher.Hash(password)
+		if err != nil {
+			return u.ID, fmt.Errorf("%w: %w", models.ErrRehash, err)
+		}
+
+		u.PasswordHash = hash
+	}
+
+	return u.ID, nil
+}
+
+func (m *UserModel) Exists(ctx context.Context, id int) (bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	return m.s.user(id) != nil, nil
+}
+
+func (m *UserModel) ExistsEmail(ctx context.Context, email string) (bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	return m.s.userByEmail(email) != nil, nil
+}
+
+func (m *UserModel) Count(ctx context.Context) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	return len(m.s.users), nil
+}
+
+func (m *UserModel) GetIDByEmail(ctx context.Context, email string) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	u := m.s.userByEmail(email)
+	if u == nil {
+		return 0, models.ErrNoRecord
+	}
+
+	return u.ID, nil
+}
+
+func (m *UserModel) UpdatePassword(ctx context.Context, email, password string) error {
+	hash, err := m.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if u := m.s.userByEmail(email); u != nil {
+		u.PasswordHash = hash
+	}
+
+	return nil
+}
+
+func (m *UserModel) GetProfile(ctx context.Context, id int) (*models.UserProfile, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	u := m.s.user(id)
+	if u == nil {
+		return nil, models.ErrNoRecord
+	}
+
+	return &models.UserProfile{Name: u.Name, Email: u.Email}, nil
+}
+
+// Deletes the user and everything that references them with ON DELETE CASCADE,
+// along with their sessions and the rows keyed by their email
+func (m *UserModel) Delete(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	return m.s.deleteUser(id)
+}
+
+func (s *store) deleteUser(id int) error {
+	u := s.user(id)
+	if u == nil {
+		return models.ErrNoRecord
+	}
+
+	byEmail := func(email string) bool { return !strings.EqualFold(email, u.Email) }
+
+	s.attempts = filter(s.attempts, func(a *authAttempt) bool { return byEmail(a.email) })
+	s.verifications = filter(s.verifications, func(v *models.Verification) bool { return byEmail(v.Email) })
+	s.emails = filter(s.emails, func(e *models.Email) bool { return byEmail(e.Recipient) })
+
+	for email := range s.lockouts {
+		if !byEmail(email) {
+			delete(s.lockouts, email)
+		}
+	}
+
+	s.users = filter(s.users, func(u *models.User) bool { return u.ID != id })
+	s.contacts = filter(s.contacts, func(c *contact) bool { return c.userID != id })
+	s.timeslots = filter(s.timeslots, func(t *timeslot) bool { return t.userID != id })
+	s.roster = filter(s.roster, func(r *rosterEntry) bool { return r.userID != id })
+	s.recoveryCodes = filter(s.recoveryCodes, func(c *recoveryCode) bool { return c.userID != id })
+	s.matches = filter(s.matches, func(x *match) bool {
+		return x.confirmedAt != nil || (x.reporterID != id && x.opponentID != id)
+	})
+
+	// Confirmed matches stay with the other player
+	for _, x := range s.matches {
+		for _, p := range []*int{&x.reporterID, &x.opponentID, &x.winnerID} {
+			if *p == id {
+				*p = 0
+			}
+		}
+	}
+
+	var postIDs []int
+	s.posts = filter(s.posts, func(p *models.Post) bool {
+		if p.UserID == id {
+			postIDs = append(postIDs, p.ID)
+			return false
+		}
+
+		return true
+	})
+
+	for _, postID := range postIDs {
+		s.deleteRoster(postID)
+	}
+
+	for token, session := range s.sessions {
+		if session.userID == id {
+			delete(s.sessions, token)
+		}
+	}
+
+	for hash, t := range s.loginTokens {
+		if t.userID == id {
+			delete(s.loginTokens, hash)
+		}
+	}
+
+	for key, i := range s.identities {
+		if i.userID == id {
+			delete(s.identities, key)
+		}
+	}
+
+	delete(s.totps, id)
+	delete(s.admins, id)
+	delete(s.deletions, id)
+
+	return nil
+}
+
+// Returns the elements of xs for which keep returns true
+func filter[T any](xs []T, keep func(T) bool) []T {
+	var kept []T
+	for _, x := range xs {
+		if keep(x) {
+			kept = append(kept, x)
+		}
+	}
+
+	return kept
+}
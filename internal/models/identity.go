@@ -0,0 +1,71 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type IdentityModelInterface interface {
+	Insert(ctx context.Context, issuer, subject string, userID int) error
+	GetUserID(ctx context.Context, issuer, subject string) (int, error)
+	User(ctx context.Context, userID int) ([]*Identity, error)
+}
+
+// Links accounts to users of external identity providers
+type IdentityModel struct {
+	db dbtx
+}
+
+func (m *IdentityModel) Insert(ctx context.Context, issuer, subject string, userID int) error {
+	sql := `INSERT INTO identity_ (issuer_, subject_, user_id_)
+		VALUES($1, $2, $3)
+		ON CONFLICT (issuer_, subject_) DO NOTHING;`
+
+	_, err := m.db.Exec(ctx, sql, issuer, subject, userID)
+
+	return err
+}
+
+func (m *IdentityModel) GetUserID(ctx context.Context, issuer, subject string) (int, error) {
+	var id int
+
+	sql := `SELECT user_id_ FROM identity_
+		WHERE issuer_ = $1 AND subject_ = $2;`
+
+	err := m.db.QueryRow(ctx, sql, issuer, subject).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrNoRecord
+	}
+
+	return id, err
+}
+
+type Identity struct {
+	Issuer    string
+	Subject   string
+	CreatedAt time.Time
+}
+
+func scanIdentity(row pgx.CollectableRow) (*Identity, error) {
+	var i Identity
+	err := row.Scan(&i.Issuer, &i.Subject, &i.CreatedAt)
+
+	return &i, err
+}
+
+// Returns the identities linked to the user, oldest first
+func (m *IdentityModel) User(ctx context.Context, userID int) ([]*Identity, error) {
+	sql := `SELECT issuer_, subject_, created_at_ FROM identity_
+		WHERE user_id_ = $1
+		ORDER BY created_at_;`
+
+	rows, err := m.db.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanIdentity)
+}
@@ -0,0 +1,486 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// Players may only challenge someone at most this many rungs above them
+	LadderChallengeRange = 3
+	// Unreported challenges expire after this duration
+	LadderChallengeExpiry = time.Hour * 24 * 7
+
+	eloInitialRating = 1200
+	eloK             = 32
+)
+
+type LadderModelInterface interface {
+	Standings(ctx context.Context, sportID int) ([]*LadderRung, error)
+	Join(ctx context.Context, sportID, userID int) error
+	Leave(ctx context.Context, sportID, userID int) error
+	Challenge(ctx context.Context, sportID, challengerID, opponentID int) (int, error)
+	Challenges(ctx context.Context, sportID, userID int) ([]*Challenge, error)
+	Report(ctx context.Context, sportID, challengeID, reporterID, winnerID int, score string) error
+	Reject(ctx context.Context, sportID, challengeID, userID int) error
+	Confirm(ctx context.Context, sportID, challengeID, userID int) error
+}
+
+type LadderModel struct {
+	db dbtx
+}
+
+type LadderRung struct {
+	Position int
+	UserID   int
+	UserName string
+	Rating   int
+	Wins     int
+	Losses   int
+}
+
+func scanLadderRung(row pgx.CollectableRow) (*LadderRung, error) {
+	var l LadderRung
+	err := row.Scan(
+		&l.Position,
+		&l.UserID,
+		&l.UserName,
+		&l.Rating,
+		&l.Wins,
+		&l.Losses)
+
+	return &l, err
+}
+
+func (m *LadderModel) Standings(ctx context.Context, sportID int) ([]*LadderRung, error) {
+	sql := `SELECT
+			l.position_,
+			u.id_,
+			u.name_,
+			l.rating_,
+			(SELECT COUNT(*) FROM challenge_ c
+				WHERE c.sport_id_ = l.sport_id_
+				AND c.confirmed_at_ IS NOT NULL
+				AND c.winner_id_ = l.user_id_),
+			(SELECT COUNT(*) FROM challenge_ c
+				WHERE c.sport_id_ = l.sport_id_
+				AND c.confirmed_at_ IS NOT NULL
+				AND (c.challenger_id_ = l.user_id_ OR c.opponent_id_ = l.user_id_)
+				AND c.winner_id_ IS DISTINCT FROM l.user_id_)
+		FROM ladder_ l
+		INNER JOIN user_ u
+			ON u.id_ = l.user_id_
+		WHERE l.sport_id_ = $1
+		ORDER BY l.position_;`
+
+	rows, err := m.db.Query(ctx, sql, sportID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanLadderRung)
+}
+
+// Locks the sport's ladder until the transaction ends so that concurrent
+// joins and leaves cannot hand out the same position. NO KEY UPDATE leaves
+// the foreign key checks of other tables referencing the sport unblocked.
+func lockLadder(ctx context.Context, tx *dbTx, sportID int) error {
+	var id int
+
+	sql := "SELECT id_ FROM sport_ WHERE id_ = $1 FOR NO KEY UPDATE;"
+
+	err := tx.QueryRow(ctx, sql, sportID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNoRecord
+	}
+
+	return err
+}
+
+// Adds the user to the bottom of the sport's ladder
+func (m *LadderModel) Join(ctx context.Context, sportID, userID int) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = lockLadder(ctx, tx, sportID)
+	if err != nil {
+		return err
+	}
+
+	sql := `INSERT INTO ladder_ (sport_id_, user_id_, position_, rating_)
+		SELECT $1, $2, COALESCE(MAX(position_), 0) + 1, $3
+		FROM ladder_ WHERE sport_id_ = $1
+		ON CONFLICT (sport_id_, user_id_) DO NOTHING;`
+
+	tag, err := tx.Exec(ctx, sql, sportID, userID, eloInitialRating)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrDuplicateMember
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Removes the user from the ladder, moves everyone below them up one rung
+// and cancels their open challenges
+func (m *LadderModel) Leave(ctx context.Context, sportID, userID int) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = lockLadder(ctx, tx, sportID)
+	if err != nil {
+		return err
+	}
+
+	var position int
+
+	sql := `DELETE FROM ladder_ WHERE sport_id_ = $1 AND user_id_ = $2
+		RETURNING position_;`
+
+	err = tx.QueryRow(ctx, sql, sportID, userID).Scan(&position)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNoRecord
+		}
+
+		return err
+	}
+
+	sql = `UPDATE ladder_ SET position_ = position_ - 1
+		WHERE sport_id_ = $1 AND position_ > $2;`
+
+	_, err = tx.Exec(ctx, sql, sportID, position)
+	if err != nil {
+		return err
+	}
+
+	sql = `DELETE FROM challenge_
+		WHERE sport_id_ = $1 AND confirmed_at_ IS NULL
+		AND (challenger_id_ = $2 OR opponent_id_ = $2);`
+
+	_, err = tx.Exec(ctx, sql, sportID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (m *LadderModel) position(ctx context.Context, tx *dbTx, sportID, userID int) (int, error) {
+	var position int
+
+	sql := `SELECT position_ FROM ladder_
+		WHERE sport_id_ = $1 AND user_id_ = $2 FOR UPDATE;`
+
+	err := tx.QueryRow(ctx, sql, sportID, userID).Scan(&position)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrNoRecord
+	}
+
+	return position, err
+}
+
+// An open challenge has not been confirmed, and has either been reported or
+// not yet expired.
+const openChallengeCondition = `confirmed_at_ IS NULL
+	AND (reported_at_ IS NOT NULL OR expires_at_ > NOW())`
+
+func (m *LadderModel) Challenge(ctx context.Context, sportID, challengerID, opponentID int) (int, error) {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	challengerPos, err := m.position(ctx, tx, sportID, challengerID)
+	if err != nil {
+		return 0, err
+	}
+
+	opponentPos, err := m.position(ctx, tx, sportID, opponentID)
+	if err != nil {
+		return 0, err
+	}
+
+	diff := challengerPos - opponentPos
+	if diff < 1 || diff > LadderChallengeRange {
+		return 0, ErrInvalidChallenge
+	}
+
+	var active bool
+
+	sql := `SELECT EXISTS(SELECT true FROM challenge_
+		WHERE sport_id_ = $1 AND ` + openChallengeCondition + `
+		AND (challenger_id_ IN ($2, $3) OR opponent_id_ IN ($2, $3)));`
+
+	err = tx.QueryRow(ctx, sql, sportID, challengerID, opponentID).Scan(&active)
+	if err != nil {
+		return 0, err
+	}
+
+	if active {
+		return 0, ErrActiveChallenge
+	}
+
+	var id int
+
+	sql = `INSERT INTO challenge_
+		(sport_id_, challenger_id_, opponent_id_, expires_at_)
+		VALUES($1, $2, $3, $4) RETURNING id_;`
+
+	expiry := time.Now().Add(LadderChallengeExpiry)
+	err = tx.QueryRow(ctx, sql, sportID, challengerID, opponentID, expiry).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit(ctx)
+}
+
+type Challenge struct {
+	ID             int
+	SportID        int
+	ChallengerID   int
+	ChallengerName string
+	OpponentID     int
+	OpponentName   string
+	WinnerID       *int
+	Score          *string
+	ReportedByID   *int
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+}
+
+func (c *Challenge) IsReported() bool {
+	return c.ReportedByID != nil
+}
+
+func (c *Challenge) IsReportedBy(userID int) bool {
+	return c.ReportedByID != nil && *c.ReportedByID == userID
+}
+
+func (c *Challenge) IsWonBy(userID int) bool {
+	return c.WinnerID != nil && *c.WinnerID == userID
+}
+
+func scanChallenge(row pgx.CollectableRow) (*Challenge, error) {
+	var c Challenge
+	err := row.Scan(
+		&c.ID,
+		&c.SportID,
+		&c.ChallengerID,
+		&c.ChallengerName,
+		&c.OpponentID,
+		&c.OpponentName,
+		&c.WinnerID,
+		&c.Score,
+		&c.ReportedByID,
+		&c.ExpiresAt,
+		&c.CreatedAt)
+
+	return &c, err
+}
+
+// Returns the user's open challenges on the sport's ladder
+func (m *LadderModel) Challenges(ctx context.Context, sportID, userID int) ([]*Challenge, error) {
+	sql := `SELECT
+			c.id_,
+			c.sport_id_,
+			c.challenger_id_,
+			cu.name_,
+			c.opponent_id_,
+			ou.name_,
+			c.winner_id_,
+			c.score_,
+			c.reported_by_id_,
+			c.expires_at_,
+			c.created_at_
+		FROM challenge_ c
+		INNER JOIN user_ cu
+			ON cu.id_ = c.challenger_id_
+		INNER JOIN user_ ou
+			ON ou.id_ = c.opponent_id_
+		WHERE c.sport_id_ = $1
+		AND (c.challenger_id_ = $2 OR c.opponent_id_ = $2)
+		AND ` + openChallengeCondition + `
+		ORDER BY c.created_at_;`
+
+	rows, err := m.db.Query(ctx, sql, sportID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanChallenge)
+}
+
+// Records the result of a challenge. The other player must confirm it before
+// the ladder is updated.
+func (m *LadderModel) Report(ctx context.Context, sportID, challengeID, reporterID, winnerID int, score string) error {
+	sql := `UPDATE challenge_ SET
+			winner_id_ = $3,
+			score_ = $4,
+			reported_by_id_ = $2,
+			reported_at_ = NOW()
+		WHERE id_ = $1 AND sport_id_ = $5
+		AND (challenger_id_ = $2 OR opponent_id_ = $2)
+		AND (challenger_id_ = $3 OR opponent_id_ = $3)
+		AND reported_at_ IS NULL
+		AND ` + openChallengeCondition + `;`
+
+	tag, err := m.db.Exec(ctx, sql,
+		challengeID, reporterID, winnerID, score, sportID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// Clears a reported result so that it can be reported again
+func (m *LadderModel) Reject(ctx context.Context, sportID, challengeID, userID int) error {
+	sql := `UPDATE challenge_ SET
+			winner_id_ = NULL,
+			score_ = NULL,
+			reported_by_id_ = NULL,
+			reported_at_ = NULL
+		WHERE id_ = $1 AND sport_id_ = $3
+		AND (challenger_id_ = $2 OR opponent_id_ = $2)
+		AND reported_by_id_ <> $2
+		AND confirmed_at_ IS NULL;`
+
+	tag, err := m.db.Exec(ctx, sql, challengeID, userID, sportID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// Confirms a reported result, updates both players' ratings and, if the
+// challenger won, moves them into the opponent's rung.
+func (m *LadderModel) Confirm(ctx context.Context, sportID, challengeID, userID int) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = lockLadder(ctx, tx, sportID)
+	if err != nil {
+		return err
+	}
+
+	var challengerID, opponentID, winnerID int
+
+	sql := `UPDATE challenge_ SET confirmed_at_ = NOW()
+		WHERE id_ = $1 AND sport_id_ = $3
+		AND (challenger_id_ = $2 OR opponent_id_ = $2)
+		AND reported_by_id_ <> $2
+		AND confirmed_at_ IS NULL
+		RETURNING challenger_id_, opponent_id_, winner_id_;`
+
+	err = tx.QueryRow(ctx, sql, challengeID, userID, sportID).Scan(
+		&challengerID, &opponentID, &winnerID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNoRecord
+		}
+
+		return err
+	}
+
+	loserID := challengerID
+	if winnerID == challengerID {
+		loserID = opponentID
+	}
+
+	var winnerRating, loserRating int
+
+	sql = `SELECT rating_ FROM ladder_
+		WHERE sport_id_ = $1 AND user_id_ = $2 FOR UPDATE;`
+
+	err = tx.QueryRow(ctx, sql, sportID, winnerID).Scan(&winnerRating)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(ctx, sql, sportID, loserID).Scan(&loserRating)
+	if err != nil {
+		return err
+	}
+
+	winnerRating, loserRating = eloRatings(winnerRating, loserRating)
+
+	sql = `UPDATE ladder_ SET rating_ = $3
+		WHERE sport_id_ = $1 AND user_id_ = $2;`
+
+	_, err = tx.Exec(ctx, sql, sportID, winnerID, winnerRating)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, sql, sportID, loserID, loserRating)
+	if err != nil {
+		return err
+	}
+
+	if winnerID == challengerID {
+		challengerPos, err := m.position(ctx, tx, sportID, challengerID)
+		if err != nil {
+			return err
+		}
+
+		opponentPos, err := m.position(ctx, tx, sportID, opponentID)
+		if err != nil {
+			return err
+		}
+
+		// Everyone from the opponent down to the challenger moves down one
+		sql = `UPDATE ladder_ SET position_ = position_ + 1
+			WHERE sport_id_ = $1 AND position_ >= $2 AND position_ < $3;`
+
+		_, err = tx.Exec(ctx, sql, sportID, opponentPos, challengerPos)
+		if err != nil {
+			return err
+		}
+
+		sql = `UPDATE ladder_ SET position_ = $3
+			WHERE sport_id_ = $1 AND user_id_ = $2;`
+
+		_, err = tx.Exec(ctx, sql, sportID, challengerID, opponentPos)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Returns the new Elo ratings of the winner and loser of a match
+func eloRatings(winner, loser int) (int, int) {
+	expected := 1 / (1 + math.Pow(10, float64(loser-winner)/400))
+	delta := int(math.Round(eloK * (1 - expected)))
+
+	return winner + delta, loser - delta
+}
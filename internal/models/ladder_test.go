@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/micahco/racket-connections/internal/assert"
+)
+
+func TestEloRatings(t *testing.T) {
+	tests := []struct {
+		name       string
+		winner     int
+		loser      int
+		wantWinner int
+		wantLoser  int
+	}{
+		{
+			name:       "Equal",
+			winner:     1200,
+			loser:      1200,
+			wantWinner: 1216,
+			wantLoser:  1184,
+		},
+		{
+			name:       "Favourite wins",
+			winner:     1400,
+			loser:      1200,
+			wantWinner: 1408,
+			wantLoser:  1192,
+		},
+		{
+			name:       "Upset",
+			winner:     1200,
+			loser:      1400,
+			wantWinner: 1224,
+			wantLoser:  1376,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			winner, loser := eloRatings(tt.winner, tt.loser)
+			assert.Equal(t, winner, tt.wantWinner)
+			assert.Equal(t, loser, tt.wantLoser)
+		})
+	}
+}
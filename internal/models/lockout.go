@@ -0,0 +1,77 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type LockoutModelInterface interface {
+	Insert(ctx context.Context, email string, until time.Time) error
+	Get(ctx context.Context, email string) (*Lockout, error)
+	Delete(ctx context.Context, email string) error
+}
+
+type LockoutModel struct {
+	db dbtx
+}
+
+type Lockout struct {
+	Email     string
+	Until     time.Time
+	CreatedAt time.Time
+}
+
+func (l *Lockout) IsActive() bool {
+	return time.Now().Before(l.Until)
+}
+
+func scanLockout(row pgx.CollectableRow) (*Lockout, error) {
+	var l Lockout
+	err := row.Scan(
+		&l.Email,
+		&l.Until,
+		&l.CreatedAt)
+
+	return &l, err
+}
+
+// Prevents logins for the email until the given time, replacing any previous
+// lockout.
+func (m *LockoutModel) Insert(ctx context.Context, email string, until time.Time) error {
+	sql := `INSERT INTO lockout_ (email_, until_)
+		VALUES($1, $2)
+		ON CONFLICT (email_) DO UPDATE
+		SET until_ = EXCLUDED.until_, created_at_ = NOW();`
+
+	_, err := m.db.Exec(ctx, sql, email, until)
+
+	return err
+}
+
+// Returns the most recent lockout for the email, which may have expired
+func (m *LockoutModel) Get(ctx context.Context, email string) (*Lockout, error) {
+	sql := "SELECT * FROM lockout_ WHERE email_ = $1;"
+
+	rows, err := m.db.Query(ctx, sql, email)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := pgx.CollectOneRow(rows, scanLockout)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+
+	return l, err
+}
+
+func (m *LockoutModel) Delete(ctx context.Context, email string) error {
+	sql := "DELETE FROM lockout_ WHERE email_ = $1;"
+
+	_, err := m.db.Exec(ctx, sql, email)
+
+	return err
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	loginTokenExpiration = 15 * time.Minute
+)
+
+type LoginTokenModelInterface interface {
+	Insert(ctx context.Context, tokenHash string, userID int) error
+	Consume(ctx context.Context, tokenHash string) (int, error)
+}
+
+// Single use tokens emailed to users to login without a password. Only the
+// hash of the token is stored.
+type LoginTokenModel struct {
+	db dbtx
+}
+
+// Stores the hash of a new token for the user and purges their expired ones
+func (m *LoginTokenModel) Insert(ctx context.Context, tokenHash string, userID int) error {
+	expiry := time.Now().Add(loginTokenExpiration)
+
+	sql := `WITH purged AS (
+			DELETE FROM login_token_ WHERE user_id_ = $2 AND expiry_ < NOW()
+		)
+		INSERT INTO login_token_
+		(token_hash_, user_id_, expiry_)
+		VALUES($1, $2, $3);`
+
+	_, err := m.db.Exec(ctx, sql, tokenHash, userID, expiry)
+
+	return err
+}
+
+// Deletes the token and returns the ID of the user it belongs to
+func (m *LoginTokenModel) Consume(ctx context.Context, tokenHash string) (int, error) {
+	var userID int
+	var expiry time.Time
+
+	sql := `DELETE FROM login_token_ WHERE token_hash_ = $1
+		RETURNING user_id_, expiry_;`
+
+	err := m.db.QueryRow(ctx, sql, tokenHash).Scan(&userID, &expiry)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+
+		return 0, err
+	}
+
+	if time.Now().After(expiry) {
+		return 0, ErrExpiredVerification
+	}
+
+	return userID, nil
+}
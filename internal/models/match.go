@@ -0,0 +1,195 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type MatchModelInterface interface {
+	Insert(ctx context.Context, sportID, reporterID, opponentID, winnerID int, playedOn time.Time, sets []MatchSet) (int, error)
+	Confirm(ctx context.Context, id, opponentID int) error
+	Reject(ctx context.Context, id, userID int) error
+	User(ctx context.Context, userID int) ([]*UserMatch, error)
+	HeadToHead(ctx context.Context, userID int) ([]*HeadToHead, error)
+}
+
+type MatchModel struct {
+	db dbtx
+}
+
+// Records a match reported by a player. The opponent must confirm it before
+// it counts towards their history.
+func (m *MatchModel) Insert(ctx context.Context, sportID, reporterID, opponentID, winnerID int, playedOn time.Time, sets []MatchSet) (int, error) {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int
+
+	sql := `INSERT INTO match_
+		(sport_id_, reporter_id_, opponent_id_, winner_id_, played_on_)
+		VALUES($1, $2, $3, $4, $5) RETURNING id_;`
+
+	err = tx.QueryRow(ctx, sql,
+		sportID, reporterID, opponentID, winnerID, playedOn).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	sql = `INSERT INTO match_set_
+		(match_id_, number_, reporter_score_, opponent_score_)
+		VALUES($1, $2, $3, $4);`
+
+	for i, s := range sets {
+		_, err = tx.Exec(ctx, sql, id, i+1, s.PlayerScore, s.OpponentScore)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return id, tx.Commit(ctx)
+}
+
+func (m *MatchModel) Confirm(ctx context.Context, id, opponentID int) error {
+	sql := `UPDATE match_ SET confirmed_at_ = NOW()
+		WHERE id_ = $1 AND opponent_id_ = $2 AND confirmed_at_ IS NULL;`
+
+	tag, err := m.db.Exec(ctx, sql, id, opponentID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// Deletes an unconfirmed match. Either player may do so.
+func (m *MatchModel) Reject(ctx context.Context, id, userID int) error {
+	sql := `DELETE FROM match_
+		WHERE id_ = $1 AND (reporter_id_ = $2 OR opponent_id_ = $2)
+		AND confirmed_at_ IS NULL;`
+
+	tag, err := m.db.Exec(ctx, sql, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// A match from the perspective of one of its players
+type UserMatch struct {
+	ID           int
+	Sport        string
+	OpponentID   int
+	OpponentName string
+	Score        string
+	Won          bool
+	IsReporter   bool
+	IsConfirmed  bool
+	PlayedOn     time.Time
+}
+
+func scanUserMatch(row pgx.CollectableRow) (*UserMatch, error) {
+	var u UserMatch
+	err := row.Scan(
+		&u.ID,
+		&u.Sport,
+		&u.OpponentID,
+		&u.OpponentName,
+		&u.Score,
+		&u.Won,
+		&u.IsReporter,
+		&u.IsConfirmed,
+		&u.PlayedOn)
+
+	return &u, err
+}
+
+// Returns the user's matches, most recent first, with scores from their
+// perspective
+func (m *MatchModel) User(ctx context.Context, userID int) ([]*UserMatch, error) {
+	sql := `SELECT
+			m.id_,
+			s.name_,
+			COALESCE(o.id_, 0),
+			COALESCE(o.name_, 'Deleted user'),
+			(SELECT string_agg(
+				CASE WHEN m.reporter_id_ = $1
+					THEN ms.reporter_score_ || '-' || ms.opponent_score_
+					ELSE ms.opponent_score_ || '-' || ms.reporter_score_
+				END, ' ' ORDER BY ms.number_)
+				FROM match_set_ ms WHERE ms.match_id_ = m.id_),
+			m.winner_id_ IS NOT DISTINCT FROM $1,
+			m.reporter_id_ IS NOT DISTINCT FROM $1,
+			m.confirmed_at_ IS NOT NULL,
+			m.played_on_
+		FROM match_ m
+		INNER JOIN sport_ s
+			ON s.id_ = m.sport_id_
+		LEFT JOIN user_ o
+			ON o.id_ = CASE WHEN m.reporter_id_ = $1
+				THEN m.opponent_id_ ELSE m.reporter_id_ END
+		WHERE m.reporter_id_ = $1 OR m.opponent_id_ = $1
+		ORDER BY m.played_on_ DESC, m.id_ DESC;`
+
+	rows, err := m.db.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanUserMatch)
+}
+
+type HeadToHead struct {
+	OpponentID   int
+	OpponentName string
+	Wins         int
+	Losses       int
+}
+
+func scanHeadToHead(row pgx.CollectableRow) (*HeadToHead, error) {
+	var h HeadToHead
+	err := row.Scan(
+		&h.OpponentID,
+		&h.OpponentName,
+		&h.Wins,
+		&h.Losses)
+
+	return &h, err
+}
+
+// Returns the user's confirmed record against each opponent
+func (m *MatchModel) HeadToHead(ctx context.Context, userID int) ([]*HeadToHead, error) {
+	sql := `SELECT
+			COALESCE(o.id_, 0),
+			COALESCE(o.name_, 'Deleted user'),
+			COUNT(*) FILTER (WHERE m.winner_id_ = $1),
+			COUNT(*) FILTER (WHERE m.winner_id_ IS DISTINCT FROM $1)
+		FROM match_ m
+		LEFT JOIN user_ o
+			ON o.id_ = CASE WHEN m.reporter_id_ = $1
+				THEN m.opponent_id_ ELSE m.reporter_id_ END
+		WHERE (m.reporter_id_ = $1 OR m.opponent_id_ = $1)
+		AND m.confirmed_at_ IS NOT NULL
+		GROUP BY o.id_, o.name_
+		ORDER BY COUNT(*) DESC, o.name_;`
+
+	rows, err := m.db.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanHeadToHead)
+}
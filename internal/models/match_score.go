@@ -0,0 +1,189 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The score of a single set or game from the reporting player's perspective
+type MatchSet struct {
+	PlayerScore   int
+	OpponentScore int
+}
+
+// Parses scores such as "6-4 3-6 7-5", where the first number of each set is
+// the reporting player's score.
+func ParseMatchSets(s string) ([]MatchSet, error) {
+	var sets []MatchSet
+	for _, field := range strings.Fields(strings.ReplaceAll(s, ",", " ")) {
+		a, b, found := strings.Cut(field, "-")
+		if !found {
+			return nil, fmt.Errorf("%q is not in the form 6-4", field)
+		}
+
+		player, err := strconv.Atoi(a)
+		if err != nil || player < 0 {
+			return nil, fmt.Errorf("%q is not in the form 6-4", field)
+		}
+
+		opponent, err := strconv.Atoi(b)
+		if err != nil || opponent < 0 {
+			return nil, fmt.Errorf("%q is not in the form 6-4", field)
+		}
+
+		sets = append(sets, MatchSet{player, opponent})
+	}
+
+	if len(sets) == 0 {
+		return nil, errors.New("at least one set or game is required")
+	}
+
+	return sets, nil
+}
+
+// Describes how games or sets are won in a sport
+type scoreRule struct {
+	unit string
+	// Points needed to win a game
+	target int
+	// Final game target if it differs, such as the racquetball tiebreaker
+	finalTarget int
+	// Required winning margin
+	winBy int
+	// Score at which the game ends regardless of margin, 0 for none
+	cap int
+	// Permitted best-of lengths
+	bestOf []int
+}
+
+var scoreRules = map[string]scoreRule{
+	"badminton":    {unit: "game", target: 21, winBy: 2, cap: 30, bestOf: []int{3}},
+	"table tennis": {unit: "game", target: 11, winBy: 2, bestOf: []int{3, 5, 7}},
+	"pickleball":   {unit: "game", target: 11, winBy: 2, bestOf: []int{1, 3}},
+	"racquetball":  {unit: "game", target: 15, finalTarget: 11, winBy: 1, bestOf: []int{3}},
+	"squash":       {unit: "game", target: 11, winBy: 2, bestOf: []int{3, 5}},
+}
+
+// Validates the sets of a match under the sport's rules and reports whether
+// the reporting player won.
+func ValidateMatchSets(sport string, sets []MatchSet) (bool, error) {
+	if sport == "tennis" {
+		return validateTennisSets(sets)
+	}
+
+	rule, ok := scoreRules[sport]
+	if !ok {
+		return false, fmt.Errorf("unknown sport %q", sport)
+	}
+
+	bestOf, won, err := matchResult(sets, rule.bestOf, rule.unit)
+	if err != nil {
+		return false, err
+	}
+
+	for i, s := range sets {
+		target := rule.target
+		if rule.finalTarget != 0 && i == bestOf-1 {
+			target = rule.finalTarget
+		}
+
+		if !validGame(s, target, rule.winBy, rule.cap) {
+			return false, fmt.Errorf("%s %d (%d-%d) is not a valid %s score",
+				rule.unit, i+1, s.PlayerScore, s.OpponentScore, sport)
+		}
+	}
+
+	return won, nil
+}
+
+func validGame(s MatchSet, target, winBy, cap int) bool {
+	high, low := s.PlayerScore, s.OpponentScore
+	if low > high {
+		high, low = low, high
+	}
+
+	if cap != 0 && high == cap {
+		return low >= cap-winBy
+	}
+
+	if high == target {
+		return high-low >= winBy
+	}
+
+	// Extended games must end as soon as the margin is reached
+	return high > target && high-low == winBy
+}
+
+func validateTennisSets(sets []MatchSet) (bool, error) {
+	_, won, err := matchResult(sets, []int{3, 5}, "set")
+	if err != nil {
+		return false, err
+	}
+
+	for i, s := range sets {
+		high, low := s.PlayerScore, s.OpponentScore
+		if low > high {
+			high, low = low, high
+		}
+
+		// 6-0 to 6-4, 7-5, or 7-6 after a tiebreak
+		valid := (high == 6 && low <= 4) || (high == 7 && (low == 5 || low == 6))
+		if !valid {
+			return false, fmt.Errorf("set %d (%d-%d) is not a valid tennis score",
+				i+1, s.PlayerScore, s.OpponentScore)
+		}
+	}
+
+	return won, nil
+}
+
+// Determines the length of the match from the number of sets the winner took
+// and reports whether the player won. Ensures the match was completed and that
+// no sets were played after it was decided.
+func matchResult(sets []MatchSet, bestOf []int, unit string) (int, bool, error) {
+	var won, lost int
+	for i, s := range sets {
+		if s.PlayerScore == s.OpponentScore {
+			return 0, false, fmt.Errorf("%s %d cannot be tied", unit, i+1)
+		}
+
+		if s.PlayerScore > s.OpponentScore {
+			won++
+		} else {
+			lost++
+		}
+	}
+
+	if won == lost {
+		return 0, false, fmt.Errorf("the match is incomplete: both players won %d %ss", won, unit)
+	}
+
+	needed := max(won, lost)
+	n := 2*needed - 1
+
+	permitted := false
+	for _, b := range bestOf {
+		permitted = permitted || b == n
+	}
+
+	if !permitted {
+		return 0, false, fmt.Errorf("a match cannot be won with %d %ss", needed, unit)
+	}
+
+	won, lost = 0, 0
+	for i, s := range sets {
+		if won == needed || lost == needed {
+			return 0, false, fmt.Errorf("%s %d was played after the match was decided", unit, i+1)
+		}
+
+		if s.PlayerScore > s.OpponentScore {
+			won++
+		} else {
+			lost++
+		}
+	}
+
+	return n, won == needed, nil
+}
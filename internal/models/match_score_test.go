@@ -0,0 +1,107 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/micahco/racket-connections/internal/assert"
+)
+
+func TestValidateMatchSets(t *testing.T) {
+	tests := []struct {
+		name    string
+		sport   string
+		score   string
+		wantWon bool
+		wantErr bool
+	}{
+		{
+			name:    "Tennis straight sets",
+			sport:   "tennis",
+			score:   "6-4 7-5",
+			wantWon: true,
+		},
+		{
+			name:    "Tennis three sets with tiebreak",
+			sport:   "tennis",
+			score:   "6-7 6-3 4-6",
+			wantWon: false,
+		},
+		{
+			name:    "Tennis invalid set",
+			sport:   "tennis",
+			score:   "6-5 6-0",
+			wantErr: true,
+		},
+		{
+			name:    "Tennis incomplete",
+			sport:   "tennis",
+			score:   "6-4 4-6",
+			wantErr: true,
+		},
+		{
+			name:    "Tennis set after decided",
+			sport:   "tennis",
+			score:   "6-4 6-4 4-6",
+			wantErr: true,
+		},
+		{
+			name:    "Table tennis best of five",
+			sport:   "table tennis",
+			score:   "11-9 11-7 11-3",
+			wantWon: true,
+		},
+		{
+			name:    "Table tennis deuce",
+			sport:   "table tennis",
+			score:   "13-11 9-11 11-5",
+			wantWon: true,
+		},
+		{
+			name:    "Table tennis no margin",
+			sport:   "table tennis",
+			score:   "11-10 11-5",
+			wantErr: true,
+		},
+		{
+			name:    "Table tennis overlong deuce",
+			sport:   "table tennis",
+			score:   "14-11 11-5",
+			wantErr: true,
+		},
+		{
+			name:    "Badminton capped",
+			sport:   "badminton",
+			score:   "30-29 21-15",
+			wantWon: true,
+		},
+		{
+			name:    "Racquetball tiebreaker",
+			sport:   "racquetball",
+			score:   "15-10 12-15 9-11",
+			wantWon: false,
+		},
+		{
+			name:    "Pickleball single game",
+			sport:   "pickleball",
+			score:   "11-4",
+			wantWon: true,
+		},
+		{
+			name:    "Tied game",
+			sport:   "squash",
+			score:   "11-11",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sets, err := ParseMatchSets(tt.score)
+			assert.Equal(t, err, nil)
+
+			won, err := ValidateMatchSets(tt.sport, sets)
+			assert.Equal(t, err != nil, tt.wantErr)
+			assert.Equal(t, won, tt.wantWon)
+		})
+	}
+}
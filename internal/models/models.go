@@ -1,25 +1,67 @@
 package models
 
-import "github.com/jackc/pgx/v5/pgxpool"
+import (
+	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/micahco/racket-connections/internal/crypto"
+)
+
+// Models are interfaces so handlers can be tested with fakes
 type Models struct {
-	Post         *PostModel
-	Skill        *SkillLevelModel
-	Sport        *SportModel
-	User         *UserModel
-	Contact      *ContactModel
-	Timeslot     *TimeslotModel
-	Verification *VerificationModel
+	Post         PostModelInterface
+	PostFormat   PostFormatModelInterface
+	Roster       RosterModelInterface
+	Ladder       LadderModelInterface
+	Match        MatchModelInterface
+	Tournament   TournamentModelInterface
+	Skill        SkillLevelModelInterface
+	Sport        SportModelInterface
+	User         UserModelInterface
+	Contact      ContactModelInterface
+	Timeslot     TimeslotModelInterface
+	Verification VerificationModelInterface
+	AuthAttempt  AuthAttemptModelInterface
+	Lockout      LockoutModelInterface
+	TOTP         TOTPModelInterface
+	LoginToken   LoginTokenModelInterface
+	Identity     IdentityModelInterface
+	Session      SessionModelInterface
+	Email        EmailModelInterface
+	Admin        AdminModelInterface
+	Deletion     DeletionModelInterface
+	Tx           TxModelInterface
+}
+
+// Every query is cancelled after timeout, or sooner if the caller's context is
+// done
+func New(pool *pgxpool.Pool, hasher crypto.PasswordHasher, timeout time.Duration) Models {
+	return newModels(&db{pool, timeout}, hasher)
 }
 
-func New(pool *pgxpool.Pool) Models {
+func newModels(db dbtx, hasher crypto.PasswordHasher) Models {
 	return Models{
-		Post:         &PostModel{pool},
-		Skill:        &SkillLevelModel{pool},
-		Sport:        &SportModel{pool},
-		User:         &UserModel{pool},
-		Contact:      &ContactModel{pool},
-		Timeslot:     &TimeslotModel{pool},
-		Verification: &VerificationModel{pool},
+		Post:         &PostModel{db},
+		PostFormat:   &PostFormatModel{db},
+		Roster:       &RosterModel{db},
+		Ladder:       &LadderModel{db},
+		Match:        &MatchModel{db},
+		Tournament:   &TournamentModel{db},
+		Skill:        &SkillLevelModel{db},
+		Sport:        &SportModel{db},
+		User:         &UserModel{db, hasher},
+		Contact:      &ContactModel{db},
+		Timeslot:     &TimeslotModel{db},
+		Verification: &VerificationModel{db},
+		AuthAttempt:  &AuthAttemptModel{db},
+		Lockout:      &LockoutModel{db},
+		TOTP:         &TOTPModel{db},
+		LoginToken:   &LoginTokenModel{db},
+		Identity:     &IdentityModel{db},
+		Session:      &SessionModel{db},
+		Email:        &EmailModel{db},
+		Admin:        &AdminModel{db},
+		Deletion:     &DeletionModel{db},
+		Tx:           &TxModel{db, hasher},
 	}
 }
@@ -7,42 +7,55 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+type PostModelInterface interface {
+	Insert(ctx context.Context, userID, sportID, skillLevelID, formatID, playersNeeded int, comment string) (int, error)
+	GetID(ctx context.Context, userID, sportID int) (int, error)
+	GetUserID(ctx context.Context, id int) (int, error)
+	Delete(ctx context.Context, id int) error
+	Fetch(ctx context.Context, sports []string, timeslots []Timeslot) ([]*PostCard, error)
+	User(ctx context.Context, userID int) ([]*ProfilePost, error)
+	GetDetails(ctx context.Context, id int) (*PostDetails, error)
+	CountBySport(ctx context.Context) ([]*SportCount, error)
+}
+
 type PostModel struct {
-	pool *pgxpool.Pool
+	db dbtx
 }
 
 type Post struct {
-	ID           int
-	Comment      string
-	CreatedAt    time.Time
-	UserID       int
-	SportID      int
-	SkillLevelID int
+	ID            int
+	Comment       string
+	CreatedAt     time.Time
+	UserID        int
+	SportID       int
+	SkillLevelID  int
+	FormatID      int
+	PlayersNeeded int
+	ClosedAt      *time.Time
 }
 
-func (m *PostModel) Insert(userID, sportID, skillLevelID int, comment string) (int, error) {
+func (m *PostModel) Insert(ctx context.Context, userID, sportID, skillLevelID, formatID, playersNeeded int, comment string) (int, error) {
 	var id int
 
 	sql := `INSERT INTO post_
-		(user_id_, sport_id_, skill_level_id_, comment_)
-		VALUES($1, $2, $3, $4) RETURNING id_;`
+		(user_id_, sport_id_, skill_level_id_, format_id_, players_needed_, comment_)
+		VALUES($1, $2, $3, $4, $5, $6) RETURNING id_;`
 
-	err := m.pool.QueryRow(context.Background(), sql,
-		userID, sportID, skillLevelID, comment).Scan(&id)
+	err := m.db.QueryRow(ctx, sql,
+		userID, sportID, skillLevelID, formatID, playersNeeded, comment).Scan(&id)
 
 	return id, err
 }
 
-func (m *PostModel) GetID(userID, sportID int) (int, error) {
+func (m *PostModel) GetID(ctx context.Context, userID, sportID int) (int, error) {
 	var id int
 
 	sql := `SELECT id_ FROM post_ WHERE
 		user_id_ = $1 AND sport_id_ = $2;`
 
-	err := m.pool.QueryRow(context.Background(), sql, userID, sportID).Scan(&id)
+	err := m.db.QueryRow(ctx, sql, userID, sportID).Scan(&id)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return 0, ErrNoRecord
 	}
@@ -50,20 +63,23 @@ func (m *PostModel) GetID(userID, sportID int) (int, error) {
 	return id, err
 }
 
-func (m *PostModel) GetUserID(id int) (int, error) {
+func (m *PostModel) GetUserID(ctx context.Context, id int) (int, error) {
 	var userID int
 
 	sql := "SELECT user_id_ FROM post_ WHERE id_ = $1;"
 
-	err := m.pool.QueryRow(context.Background(), sql, id).Scan(&userID)
+	err := m.db.QueryRow(ctx, sql, id).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrNoRecord
+	}
 
 	return userID, err
 }
 
-func (m *PostModel) Delete(id int) error {
+func (m *PostModel) Delete(ctx context.Context, id int) error {
 	sql := "DELETE FROM post_ WHERE id_ = $1;"
 
-	_, err := m.pool.Exec(context.Background(), sql, id)
+	_, err := m.db.Exec(ctx, sql, id)
 
 	return err
 }
@@ -74,6 +90,7 @@ type PostCard struct {
 	Sport      string
 	UserName   string
 	SkillLevel string
+	Format     string
 }
 
 func scanPostCard(row pgx.CollectableRow) (*PostCard, error) {
@@ -83,24 +100,28 @@ func scanPostCard(row pgx.CollectableRow) (*PostCard, error) {
 		&p.CreatedAt,
 		&p.Sport,
 		&p.UserName,
-		&p.SkillLevel)
+		&p.SkillLevel,
+		&p.Format)
 	return &p, err
 }
 
-func (m *PostModel) Fetch(sports []string, timeslots []Timeslot) ([]*PostCard, error) {
+func (m *PostModel) Fetch(ctx context.Context, sports []string, timeslots []Timeslot) ([]*PostCard, error) {
 	sql := `SELECT DISTINCT
 				post_.id_,
 				post_.created_at_,
 				sport_.name_,
 				user_.name_,
-				skill_level_.name_
+				skill_level_.name_,
+				post_format_.name_
 			FROM post_
 			INNER JOIN sport_
 				ON sport_.id_ = post_.sport_id_
 			INNER JOIN user_
 				ON user_.id_ = post_.user_id_
 			INNER JOIN skill_level_
-				ON skill_level_.id_ = post_.skill_level_id_`
+				ON skill_level_.id_ = post_.skill_level_id_
+			INNER JOIN post_format_
+				ON post_format_.id_ = post_.format_id_`
 
 	if len(timeslots) != 0 {
 		sql += `
@@ -112,32 +133,27 @@ func (m *PostModel) Fetch(sports []string, timeslots []Timeslot) ([]*PostCard, e
 				ON time_of_day_.id_ = timeslot_.time_id_`
 	}
 
-	var args []any
-	if len(sports) != 0 || len(timeslots) != 0 {
-		sql += "\nWHERE\n"
-
-		if len(sports) != 0 {
-			sql += "sport_.name_ = ANY ($1)"
-			args = append(args, sports)
-			if len(timeslots) != 0 {
-				sql += "\nAND ("
-			}
-		}
+	// Closed posts already have a full roster
+	sql += "\nWHERE post_.closed_at_ IS NULL\n"
 
-		if len(timeslots) != 0 {
-			for i, t := range timeslots {
-				if i != 0 {
-					sql += " OR\n"
-				}
+	var args []any
+	if len(sports) != 0 {
+		sql += "AND sport_.name_ = ANY ($1)\n"
+		args = append(args, sports)
+	}
 
-				idx := len(args)
-				sql += fmt.Sprintf(`day_of_week_.abbrev_ = $%d AND time_of_day_.abbrev_ = $%d`, idx+1, idx+2)
-				args = append(args, t.Day.Abbrev, t.Time.Abbrev)
-			}
-			if len(sports) != 0 {
-				sql += ")"
+	if len(timeslots) != 0 {
+		sql += "AND ("
+		for i, t := range timeslots {
+			if i != 0 {
+				sql += " OR\n"
 			}
+
+			idx := len(args)
+			sql += fmt.Sprintf(`day_of_week_.abbrev_ = $%d AND time_of_day_.abbrev_ = $%d`, idx+1, idx+2)
+			args = append(args, t.Day.Abbrev, t.Time.Abbrev)
 		}
+		sql += ")"
 	}
 
 	sql += "\nORDER BY post_.id_ DESC\n"
@@ -145,9 +161,9 @@ func (m *PostModel) Fetch(sports []string, timeslots []Timeslot) ([]*PostCard, e
 	var rows pgx.Rows
 	var err error
 	if len(args) > 0 {
-		rows, err = m.pool.Query(context.Background(), sql, args...)
+		rows, err = m.db.Query(ctx, sql, args...)
 	} else {
-		rows, err = m.pool.Query(context.Background(), sql)
+		rows, err = m.db.Query(ctx, sql)
 	}
 
 	if err != nil {
@@ -158,36 +174,47 @@ func (m *PostModel) Fetch(sports []string, timeslots []Timeslot) ([]*PostCard, e
 }
 
 type ProfilePost struct {
-	ID         int
-	CreatedAt  time.Time
-	Sport      string
-	SkillLevel string
+	ID            int
+	Comment       string
+	CreatedAt     time.Time
+	Sport         string
+	SkillLevel    string
+	Format        string
+	PlayersNeeded int
 }
 
 func scanProfilePost(row pgx.CollectableRow) (*ProfilePost, error) {
 	var p ProfilePost
 	err := row.Scan(
 		&p.ID,
+		&p.Comment,
 		&p.CreatedAt,
 		&p.Sport,
-		&p.SkillLevel)
+		&p.SkillLevel,
+		&p.Format,
+		&p.PlayersNeeded)
 	return &p, err
 }
 
-func (m *PostModel) User(userID int) ([]*ProfilePost, error) {
+func (m *PostModel) User(ctx context.Context, userID int) ([]*ProfilePost, error) {
 	sql := `SELECT 
 				post_.id_,
+				post_.comment_,
 				post_.created_at_,
 				sport_.name_,
-				skill_level_.name_
+				skill_level_.name_,
+				post_format_.name_,
+				post_.players_needed_
 			FROM post_
 			INNER JOIN sport_
 				ON sport_.id_ = post_.sport_id_
 			INNER JOIN skill_level_
 				ON skill_level_.id_ = post_.skill_level_id_
+			INNER JOIN post_format_
+				ON post_format_.id_ = post_.format_id_
 			WHERE user_id_ = $1;`
 
-	rows, err := m.pool.Query(context.Background(), sql, userID)
+	rows, err := m.db.Query(ctx, sql, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -201,9 +228,17 @@ type PostDetails struct {
 	CreatedAt      time.Time
 	UserID         int
 	UserName       string
+	SportID        int
 	Sport          string
 	SkillLevelID   int
 	SkillLevelName string
+	Format         string
+	PlayersNeeded  int
+	ClosedAt       *time.Time
+}
+
+func (p *PostDetails) IsClosed() bool {
+	return p.ClosedAt != nil
 }
 
 func scanPostDetails(row pgx.CollectableRow) (*PostDetails, error) {
@@ -214,22 +249,30 @@ func scanPostDetails(row pgx.CollectableRow) (*PostDetails, error) {
 		&p.CreatedAt,
 		&p.UserID,
 		&p.UserName,
+		&p.SportID,
 		&p.Sport,
 		&p.SkillLevelID,
-		&p.SkillLevelName)
+		&p.SkillLevelName,
+		&p.Format,
+		&p.PlayersNeeded,
+		&p.ClosedAt)
 	return &p, err
 }
 
-func (m *PostModel) GetDetails(id int) (*PostDetails, error) {
+func (m *PostModel) GetDetails(ctx context.Context, id int) (*PostDetails, error) {
 	sql := `SELECT
 			p.id_,
 			p.comment_,
 			p.created_at_,
 			u.id_,
 			u.name_,
+			s.id_,
 			s.name_,
 			l.id_,
-			l.name_
+			l.name_,
+			f.name_,
+			p.players_needed_,
+			p.closed_at_
 		FROM post_ p
 		INNER JOIN user_ u
 			ON u.id_ = p.user_id_
@@ -237,9 +280,11 @@ func (m *PostModel) GetDetails(id int) (*PostDetails, error) {
 			ON s.id_ = p.sport_id_
 		INNER JOIN skill_level_ l
 			ON l.id_ = p.skill_level_id_
+		INNER JOIN post_format_ f
+			ON f.id_ = p.format_id_
 		WHERE p.id_ = $1;`
 
-	rows, err := m.pool.Query(context.Background(), sql, id)
+	rows, err := m.db.Query(ctx, sql, id)
 	if err != nil {
 		return nil, err
 	}
@@ -255,3 +300,32 @@ func (m *PostModel) GetDetails(id int) (*PostDetails, error) {
 
 	return p, nil
 }
+
+type SportCount struct {
+	Sport string
+	Count int
+}
+
+func scanSportCount(row pgx.CollectableRow) (*SportCount, error) {
+	var c SportCount
+	err := row.Scan(&c.Sport, &c.Count)
+
+	return &c, err
+}
+
+// Counts the open posts for every sport
+func (m *PostModel) CountBySport(ctx context.Context) ([]*SportCount, error) {
+	sql := `SELECT s.name_, COUNT(p.id_)
+		FROM sport_ s
+		LEFT JOIN post_ p
+			ON p.sport_id_ = s.id_ AND p.closed_at_ IS NULL
+		GROUP BY s.id_, s.name_
+		ORDER BY s.id_;`
+
+	rows, err := m.db.Query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanSportCount)
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type PostFormatModelInterface interface {
+	All(ctx context.Context) ([]*PostFormat, error)
+	Get(ctx context.Context, id int) (*PostFormat, error)
+}
+
+type PostFormatModel struct {
+	db dbtx
+}
+
+type PostFormat struct {
+	ID               int
+	Name             string
+	MaxPlayersNeeded int
+}
+
+func scanPostFormat(row pgx.CollectableRow) (*PostFormat, error) {
+	var f PostFormat
+	err := row.Scan(&f.ID, &f.Name, &f.MaxPlayersNeeded)
+
+	return &f, err
+}
+
+func (m *PostFormatModel) All(ctx context.Context) ([]*PostFormat, error) {
+	sql := "SELECT * FROM post_format_ ORDER BY id_;"
+
+	rows, err := m.db.Query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanPostFormat)
+}
+
+func (m *PostFormatModel) Get(ctx context.Context, id int) (*PostFormat, error) {
+	sql := "SELECT * FROM post_format_ WHERE id_ = $1;"
+
+	rows, err := m.db.Query(ctx, sql, id)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := pgx.CollectOneRow(rows, scanPostFormat)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+
+	return f, err
+}
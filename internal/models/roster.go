@@ -0,0 +1,186 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+)
+
+type RosterModelInterface interface {
+	Insert(ctx context.Context, postID, userID int) error
+	Delete(ctx context.Context, postID, userID int) error
+	Post(ctx context.Context, postID int) ([]*RosterMember, error)
+	User(ctx context.Context, userID int) ([]*RosterPost, error)
+}
+
+type RosterModel struct {
+	db dbtx
+}
+
+// Adds the user to the post's roster. The post is closed once the roster
+// has as many members as the post needs players.
+func (m *RosterModel) Insert(ctx context.Context, postID, userID int) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var playersNeeded int
+	var closedAt *time.Time
+
+	sql := `SELECT players_needed_, closed_at_ FROM post_
+		WHERE id_ = $1 FOR UPDATE;`
+
+	err = tx.QueryRow(ctx, sql, postID).Scan(&playersNeeded, &closedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNoRecord
+		}
+
+		return err
+	}
+
+	if closedAt != nil {
+		return ErrPostClosed
+	}
+
+	sql = `INSERT INTO roster_ (post_id_, user_id_)
+		VALUES($1, $2);`
+
+	_, err = tx.Exec(ctx, sql, postID, userID)
+	if err != nil {
+		if pgErrCode(err) == pgerrcode.UniqueViolation {
+			return ErrDuplicateMember
+		}
+
+		return err
+	}
+
+	sql = `UPDATE post_ SET closed_at_ = NOW()
+		WHERE id_ = $1 AND players_needed_ <= (
+			SELECT COUNT(*) FROM roster_ WHERE post_id_ = $1);`
+
+	_, err = tx.Exec(ctx, sql, postID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Removes the user from the post's roster. A post that was closed because
+// its roster was full is reopened.
+func (m *RosterModel) Delete(ctx context.Context, postID, userID int) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the post so the roster count cannot change under a concurrent join
+	sql := "SELECT 1 FROM post_ WHERE id_ = $1 FOR UPDATE;"
+
+	_, err = tx.Exec(ctx, sql, postID)
+	if err != nil {
+		return err
+	}
+
+	sql = "DELETE FROM roster_ WHERE post_id_ = $1 AND user_id_ = $2;"
+
+	tag, err := tx.Exec(ctx, sql, postID, userID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRecord
+	}
+
+	// The roster was full before this member left
+	sql = `UPDATE post_ SET closed_at_ = NULL
+		WHERE id_ = $1 AND closed_at_ IS NOT NULL AND players_needed_ <= (
+			SELECT COUNT(*) + 1 FROM roster_ WHERE post_id_ = $1);`
+
+	_, err = tx.Exec(ctx, sql, postID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+type RosterMember struct {
+	UserID   int
+	UserName string
+	JoinedAt time.Time
+}
+
+func scanRosterMember(row pgx.CollectableRow) (*RosterMember, error) {
+	var r RosterMember
+	err := row.Scan(&r.UserID, &r.UserName, &r.JoinedAt)
+
+	return &r, err
+}
+
+func (m *RosterModel) Post(ctx context.Context, postID int) ([]*RosterMember, error) {
+	sql := `SELECT
+			u.id_,
+			u.name_,
+			r.joined_at_
+		FROM roster_ r
+		INNER JOIN user_ u
+			ON u.id_ = r.user_id_
+		WHERE r.post_id_ = $1
+		ORDER BY r.joined_at_;`
+
+	rows, err := m.db.Query(ctx, sql, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanRosterMember)
+}
+
+// A post from the perspective of a player on its roster
+type RosterPost struct {
+	PostID   int
+	Sport    string
+	PostedBy string
+	JoinedAt time.Time
+}
+
+func scanRosterPost(row pgx.CollectableRow) (*RosterPost, error) {
+	var r RosterPost
+	err := row.Scan(&r.PostID, &r.Sport, &r.PostedBy, &r.JoinedAt)
+
+	return &r, err
+}
+
+// Returns the posts the user has joined, oldest first
+func (m *RosterModel) User(ctx context.Context, userID int) ([]*RosterPost, error) {
+	sql := `SELECT
+			p.id_,
+			s.name_,
+			u.name_,
+			r.joined_at_
+		FROM roster_ r
+		INNER JOIN post_ p
+			ON p.id_ = r.post_id_
+		INNER JOIN sport_ s
+			ON s.id_ = p.sport_id_
+		INNER JOIN user_ u
+			ON u.id_ = p.user_id_
+		WHERE r.user_id_ = $1
+		ORDER BY r.joined_at_;`
+
+	rows, err := m.db.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanRosterPost)
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// How often the last seen time of a session is updated
+const sessionTouchInterval = time.Minute
+
+type SessionModelInterface interface {
+	Insert(ctx context.Context, token string, userID int, ip, userAgent string) error
+	Touch(ctx context.Context, token, ip string) error
+	User(ctx context.Context, userID int, currentToken string) ([]*Session, error)
+	Delete(ctx context.Context, token string) error
+	DeleteOthers(ctx context.Context, userID int, token string) error
+	Active(ctx context.Context) (int, error)
+}
+
+// Tracks the devices users are logged in on. Session data itself is kept in
+// the sessions table by the session manager, keyed by the same token.
+type SessionModel struct {
+	db dbtx
+}
+
+type Session struct {
+	IP         string
+	UserAgent  string
+	IsCurrent  bool
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
+func scanSession(row pgx.CollectableRow) (*Session, error) {
+	var s Session
+	err := row.Scan(
+		&s.IP,
+		&s.UserAgent,
+		&s.IsCurrent,
+		&s.CreatedAt,
+		&s.LastSeenAt)
+
+	return &s, err
+}
+
+func (m *SessionModel) Insert(ctx context.Context, token string, userID int, ip, userAgent string) error {
+	sql := `INSERT INTO session_info_
+		(token_, user_id_, ip_, user_agent_)
+		VALUES($1, $2, $3, $4)
+		ON CONFLICT (token_) DO NOTHING;`
+
+	_, err := m.db.Exec(ctx, sql, token, userID, ip, userAgent)
+
+	return err
+}
+
+// Updates the last seen time of the session at most once per interval
+func (m *SessionModel) Touch(ctx context.Context, token, ip string) error {
+	sql := `UPDATE session_info_ SET last_seen_at_ = NOW(), ip_ = $2
+		WHERE token_ = $1 AND last_seen_at_ < $3;`
+
+	_, err := m.db.Exec(ctx, sql,
+		token, ip, time.Now().Add(-sessionTouchInterval))
+
+	return err
+}
+
+// Returns the user's unexpired sessions, most recently seen first
+func (m *SessionModel) User(ctx context.Context, userID int, currentToken string) ([]*Session, error) {
+	sql := `SELECT
+			i.ip_,
+			i.user_agent_,
+			i.token_ = $2,
+			i.created_at_,
+			i.last_seen_at_
+		FROM session_info_ i
+		INNER JOIN sessions s
+			ON s.token = i.token_
+		WHERE i.user_id_ = $1 AND s.expiry > NOW()
+		ORDER BY i.token_ = $2 DESC, i.last_seen_at_ DESC;`
+
+	rows, err := m.db.Query(ctx, sql, userID, currentToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanSession)
+}
+
+func (m *SessionModel) Delete(ctx context.Context, token string) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := "DELETE FROM sessions WHERE token = $1;"
+
+	_, err = tx.Exec(ctx, sql, token)
+	if err != nil {
+		return err
+	}
+
+	sql = "DELETE FROM session_info_ WHERE token_ = $1;"
+
+	_, err = tx.Exec(ctx, sql, token)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Revokes all of the user's sessions except the one with the given token.
+// Pass an empty token to revoke every session.
+func (m *SessionModel) DeleteOthers(ctx context.Context, userID int, token string) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `DELETE FROM sessions WHERE token IN (
+			SELECT token_ FROM session_info_
+			WHERE user_id_ = $1 AND token_ <> $2
+		);`
+
+	_, err = tx.Exec(ctx, sql, userID, token)
+	if err != nil {
+		return err
+	}
+
+	sql = `DELETE FROM session_info_
+		WHERE user_id_ = $1 AND token_ <> $2;`
+
+	_, err = tx.Exec(ctx, sql, userID, token)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Counts the unexpired sessions of logged in users
+func (m *SessionModel) Active(ctx context.Context) (int, error) {
+	var n int
+
+	sql := `SELECT COUNT(*) FROM session_info_ i
+		INNER JOIN sessions s
+			ON s.token = i.token_
+		WHERE s.expiry > NOW();`
+
+	err := m.db.QueryRow(ctx, sql).Scan(&n)
+
+	return n, err
+}
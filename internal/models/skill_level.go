@@ -4,11 +4,14 @@ import (
 	"context"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+type SkillLevelModelInterface interface {
+	All(ctx context.Context) ([]*SkillLevel, error)
+}
+
 type SkillLevelModel struct {
-	pool *pgxpool.Pool
+	db dbtx
 }
 
 type SkillLevel struct {
@@ -23,10 +26,10 @@ func scanSkillLevel(row pgx.CollectableRow) (*SkillLevel, error) {
 	return &s, err
 }
 
-func (m *SkillLevelModel) All() ([]*SkillLevel, error) {
+func (m *SkillLevelModel) All(ctx context.Context) ([]*SkillLevel, error) {
 	sql := "SELECT * FROM skill_level_;"
 
-	rows, err := m.pool.Query(context.Background(), sql)
+	rows, err := m.db.Query(ctx, sql)
 	if err != nil {
 		return nil, err
 	}
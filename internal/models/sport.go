@@ -2,13 +2,19 @@ package models
 
 import (
 	"context"
+	"errors"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+type SportModelInterface interface {
+	All(ctx context.Context) ([]*Sport, error)
+	GetID(ctx context.Context, name string) (int, error)
+	Get(ctx context.Context, id int) (*Sport, error)
+}
+
 type SportModel struct {
-	pool *pgxpool.Pool
+	db dbtx
 }
 
 type Sport struct {
@@ -23,10 +29,10 @@ func scanSport(row pgx.CollectableRow) (*Sport, error) {
 	return &s, err
 }
 
-func (m *SportModel) All() ([]*Sport, error) {
+func (m *SportModel) All(ctx context.Context) ([]*Sport, error) {
 	sql := "SELECT * FROM sport_;"
 
-	rows, err := m.pool.Query(context.Background(), sql)
+	rows, err := m.db.Query(ctx, sql)
 	if err != nil {
 		return nil, err
 	}
@@ -34,12 +40,28 @@ func (m *SportModel) All() ([]*Sport, error) {
 	return pgx.CollectRows(rows, scanSport)
 }
 
-func (m *SportModel) GetID(name string) (int, error) {
+func (m *SportModel) GetID(ctx context.Context, name string) (int, error) {
 	var id int
 
 	sql := "SELECT id_ FROM sport_ WHERE name_ = $1;"
 
-	err := m.pool.QueryRow(context.Background(), sql, name).Scan(&id)
+	err := m.db.QueryRow(ctx, sql, name).Scan(&id)
 
 	return id, err
 }
+
+func (m *SportModel) Get(ctx context.Context, id int) (*Sport, error) {
+	sql := "SELECT * FROM sport_ WHERE id_ = $1;"
+
+	rows, err := m.db.Query(ctx, sql, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := pgx.CollectOneRow(rows, scanSport)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+
+	return s, err
+}
@@ -2,13 +2,21 @@ package models
 
 import (
 	"context"
+	"sort"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+type TimeslotModelInterface interface {
+	Days(ctx context.Context) ([]*DayOfWeek, error)
+	Times(ctx context.Context) ([]*TimeOfDay, error)
+	Insert(ctx context.Context, userID, dayID, timeID int) error
+	User(ctx context.Context, userID int) ([]*Timeslot, error)
+	DeleteUser(ctx context.Context, userID int) error
+}
+
 type TimeslotModel struct {
-	pool *pgxpool.Pool
+	db dbtx
 }
 
 type DayOfWeek struct {
@@ -24,10 +32,10 @@ func scanDayOfWeek(row pgx.CollectableRow) (*DayOfWeek, error) {
 	return &d, err
 }
 
-func (m *TimeslotModel) Days() ([]*DayOfWeek, error) {
+func (m *TimeslotModel) Days(ctx context.Context) ([]*DayOfWeek, error) {
 	sql := `SELECT * FROM day_of_week_`
 
-	rows, err := m.pool.Query(context.Background(), sql)
+	rows, err := m.db.Query(ctx, sql)
 	if err != nil {
 		return nil, err
 	}
@@ -48,10 +56,10 @@ func scanTimeOfDay(row pgx.CollectableRow) (*TimeOfDay, error) {
 	return &t, err
 }
 
-func (m *TimeslotModel) Times() ([]*TimeOfDay, error) {
+func (m *TimeslotModel) Times(ctx context.Context) ([]*TimeOfDay, error) {
 	sql := `SELECT * FROM time_of_day_`
 
-	rows, err := m.pool.Query(context.Background(), sql)
+	rows, err := m.db.Query(ctx, sql)
 	if err != nil {
 		return nil, err
 	}
@@ -59,12 +67,12 @@ func (m *TimeslotModel) Times() ([]*TimeOfDay, error) {
 	return pgx.CollectRows(rows, scanTimeOfDay)
 }
 
-func (m *TimeslotModel) Insert(userID, dayID, timeID int) error {
+func (m *TimeslotModel) Insert(ctx context.Context, userID, dayID, timeID int) error {
 	sql := `INSERT INTO timeslot_ 
 		(user_id_, day_id_, time_id_)
 		VALUES($1, $2, $3);`
 
-	_, err := m.pool.Exec(context.Background(), sql,
+	_, err := m.db.Exec(ctx, sql,
 		userID, dayID, timeID)
 
 	return err
@@ -76,7 +84,7 @@ type Timeslot struct {
 }
 
 // Returns a map with key of DayOfWeek ID and values list of TimeOfDay
-func (m *TimeslotModel) User(userID int) ([]*Timeslot, error) {
+func (m *TimeslotModel) User(ctx context.Context, userID int) ([]*Timeslot, error) {
 	sql := `SELECT
 			d.id_,
 			d.name_,
@@ -91,7 +99,7 @@ func (m *TimeslotModel) User(userID int) ([]*Timeslot, error) {
 			ON t.id_ = s.time_id_
 		WHERE s.user_id_ = $1;`
 
-	rows, err := m.pool.Query(context.Background(), sql, userID)
+	rows, err := m.db.Query(ctx, sql, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -122,10 +130,33 @@ func (m *TimeslotModel) User(userID int) ([]*Timeslot, error) {
 	return timeslots, nil
 }
 
-func (m *TimeslotModel) DeleteUser(userID int) error {
+func (m *TimeslotModel) DeleteUser(ctx context.Context, userID int) error {
 	sql := "DELETE FROM timeslot_ WHERE user_id_ = $1;"
 
-	_, err := m.pool.Exec(context.Background(), sql, userID)
+	_, err := m.db.Exec(ctx, sql, userID)
 
 	return err
 }
+
+// Returns the timeslots present in both a and b, ordered by day then time
+func SharedTimeslots(a, b []*Timeslot) []*Timeslot {
+	shared := make([]*Timeslot, 0)
+	for _, x := range a {
+		for _, y := range b {
+			if x.Day.ID == y.Day.ID && x.Time.ID == y.Time.ID {
+				shared = append(shared, x)
+				break
+			}
+		}
+	}
+
+	sort.Slice(shared, func(i, j int) bool {
+		if shared[i].Day.ID != shared[j].Day.ID {
+			return shared[i].Day.ID < shared[j].Day.ID
+		}
+
+		return shared[i].Time.ID < shared[j].Time.ID
+	})
+
+	return shared
+}
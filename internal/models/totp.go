@@ -0,0 +1,180 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type TOTPModelInterface interface {
+	Enable(ctx context.Context, userID int, secret string, step int64, recoveryCodeHashes []string) error
+	Exists(ctx context.Context, userID int) (bool, error)
+	Get(ctx context.Context, userID int) (*TOTP, error)
+	UseStep(ctx context.Context, userID int, step int64) error
+	UseRecoveryCode(ctx context.Context, userID int, hash string) error
+	RecoveryCodes(ctx context.Context, userID int) (int, error)
+	Disable(ctx context.Context, userID int) error
+}
+
+type TOTPModel struct {
+	db dbtx
+}
+
+type TOTP struct {
+	UserID int
+	Secret string
+	// Time step of the last accepted code, which cannot be used again
+	LastStep  int64
+	CreatedAt time.Time
+}
+
+func scanTOTP(row pgx.CollectableRow) (*TOTP, error) {
+	var t TOTP
+	err := row.Scan(
+		&t.UserID,
+		&t.Secret,
+		&t.LastStep,
+		&t.CreatedAt)
+
+	return &t, err
+}
+
+// Enables two-factor authentication for the user and replaces any recovery
+// codes with the given hashes. The step is that of the confirmation code.
+func (m *TOTPModel) Enable(ctx context.Context, userID int, secret string, step int64, recoveryCodeHashes []string) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := `INSERT INTO totp_ (user_id_, secret_, last_step_)
+		VALUES($1, $2, $3)
+		ON CONFLICT (user_id_) DO UPDATE
+		SET secret_ = EXCLUDED.secret_, last_step_ = EXCLUDED.last_step_,
+		created_at_ = NOW();`
+
+	_, err = tx.Exec(ctx, sql, userID, secret, step)
+	if err != nil {
+		return err
+	}
+
+	sql = "DELETE FROM recovery_code_ WHERE user_id_ = $1;"
+
+	_, err = tx.Exec(ctx, sql, userID)
+	if err != nil {
+		return err
+	}
+
+	sql = `INSERT INTO recovery_code_ (user_id_, code_hash_)
+		VALUES($1, $2);`
+
+	for _, h := range recoveryCodeHashes {
+		_, err = tx.Exec(ctx, sql, userID, h)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (m *TOTPModel) Exists(ctx context.Context, userID int) (bool, error) {
+	var exists bool
+
+	sql := "SELECT EXISTS(SELECT true FROM totp_ WHERE user_id_ = $1);"
+
+	err := m.db.QueryRow(ctx, sql, userID).Scan(&exists)
+
+	return exists, err
+}
+
+func (m *TOTPModel) Get(ctx context.Context, userID int) (*TOTP, error) {
+	sql := "SELECT * FROM totp_ WHERE user_id_ = $1;"
+
+	rows, err := m.db.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := pgx.CollectOneRow(rows, scanTOTP)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+
+	return t, err
+}
+
+// Marks the time step as used. Returns ErrNoRecord if a code from the same or
+// a later step has already been accepted.
+func (m *TOTPModel) UseStep(ctx context.Context, userID int, step int64) error {
+	sql := `UPDATE totp_ SET last_step_ = $2
+		WHERE user_id_ = $1 AND last_step_ < $2;`
+
+	tag, err := m.db.Exec(ctx, sql, userID, step)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// Consumes the recovery code with the given hash. Returns ErrNoRecord if it
+// does not exist or has already been used.
+func (m *TOTPModel) UseRecoveryCode(ctx context.Context, userID int, hash string) error {
+	sql := `UPDATE recovery_code_ SET used_at_ = NOW()
+		WHERE user_id_ = $1 AND code_hash_ = $2 AND used_at_ IS NULL;`
+
+	tag, err := m.db.Exec(ctx, sql, userID, hash)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// Returns the number of unused recovery codes
+func (m *TOTPModel) RecoveryCodes(ctx context.Context, userID int) (int, error) {
+	var n int
+
+	sql := `SELECT COUNT(*) FROM recovery_code_
+		WHERE user_id_ = $1 AND used_at_ IS NULL;`
+
+	err := m.db.QueryRow(ctx, sql, userID).Scan(&n)
+
+	return n, err
+}
+
+func (m *TOTPModel) Disable(ctx context.Context, userID int) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := "DELETE FROM recovery_code_ WHERE user_id_ = $1;"
+
+	_, err = tx.Exec(ctx, sql, userID)
+	if err != nil {
+		return err
+	}
+
+	sql = "DELETE FROM totp_ WHERE user_id_ = $1;"
+
+	_, err = tx.Exec(ctx, sql, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,544 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	TournamentRoundRobin        = "round robin"
+	TournamentSingleElimination = "single elimination"
+)
+
+type TournamentModelInterface interface {
+	Insert(ctx context.Context, name, format string, sportID, organizerID int) (int, error)
+	All(ctx context.Context) ([]*Tournament, error)
+	Get(ctx context.Context, id int) (*Tournament, error)
+	Players(ctx context.Context, id int) ([]*TournamentPlayer, error)
+	Register(ctx context.Context, id, userID int) error
+	Withdraw(ctx context.Context, id, userID int) error
+	Start(ctx context.Context, id, organizerID int) error
+	Matches(ctx context.Context, id int) ([]*TournamentMatch, error)
+	Result(ctx context.Context, id, matchID, userID, winnerID int, score string) error
+}
+
+type TournamentModel struct {
+	db dbtx
+}
+
+type Tournament struct {
+	ID            int
+	Name          string
+	Format        string
+	SportID       int
+	Sport         string
+	OrganizerID   int
+	OrganizerName string
+	PlayerCount   int
+	StartedAt     *time.Time
+	FinishedAt    *time.Time
+	CreatedAt     time.Time
+}
+
+func (t *Tournament) IsStarted() bool {
+	return t.StartedAt != nil
+}
+
+func (t *Tournament) IsFinished() bool {
+	return t.FinishedAt != nil
+}
+
+func scanTournament(row pgx.CollectableRow) (*Tournament, error) {
+	var t Tournament
+	err := row.Scan(
+		&t.ID,
+		&t.Name,
+		&t.Format,
+		&t.SportID,
+		&t.Sport,
+		&t.OrganizerID,
+		&t.OrganizerName,
+		&t.PlayerCount,
+		&t.StartedAt,
+		&t.FinishedAt,
+		&t.CreatedAt)
+
+	return &t, err
+}
+
+const tournamentColumns = `
+		t.id_,
+		t.name_,
+		t.format_,
+		s.id_,
+		s.name_,
+		COALESCE(u.id_, 0),
+		COALESCE(u.name_, 'Deleted user'),
+		(SELECT COUNT(*) FROM tournament_player_ p WHERE p.tournament_id_ = t.id_),
+		t.started_at_,
+		t.finished_at_,
+		t.created_at_
+	FROM tournament_ t
+	INNER JOIN sport_ s
+		ON s.id_ = t.sport_id_
+	LEFT JOIN user_ u
+		ON u.id_ = t.organizer_id_`
+
+func (m *TournamentModel) Insert(ctx context.Context, name, format string, sportID, organizerID int) (int, error) {
+	var id int
+
+	sql := `INSERT INTO tournament_
+		(name_, format_, sport_id_, organizer_id_)
+		VALUES($1, $2, $3, $4) RETURNING id_;`
+
+	err := m.db.QueryRow(ctx, sql,
+		name, format, sportID, organizerID).Scan(&id)
+
+	return id, err
+}
+
+func (m *TournamentModel) All(ctx context.Context) ([]*Tournament, error) {
+	sql := "SELECT" + tournamentColumns + `
+		ORDER BY t.finished_at_ IS NOT NULL, t.created_at_ DESC;`
+
+	rows, err := m.db.Query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanTournament)
+}
+
+func (m *TournamentModel) Get(ctx context.Context, id int) (*Tournament, error) {
+	sql := "SELECT" + tournamentColumns + `
+		WHERE t.id_ = $1;`
+
+	rows, err := m.db.Query(ctx, sql, id)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := pgx.CollectOneRow(rows, scanTournament)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+
+	return t, err
+}
+
+type TournamentPlayer struct {
+	UserID   int
+	UserName string
+	Seed     *int
+	Wins     int
+	Losses   int
+}
+
+func scanTournamentPlayer(row pgx.CollectableRow) (*TournamentPlayer, error) {
+	var p TournamentPlayer
+	err := row.Scan(
+		&p.UserID,
+		&p.UserName,
+		&p.Seed,
+		&p.Wins,
+		&p.Losses)
+
+	return &p, err
+}
+
+// Returns the tournament's players ordered by wins, then seed
+func (m *TournamentModel) Players(ctx context.Context, id int) ([]*TournamentPlayer, error) {
+	sql := `SELECT
+			u.id_,
+			u.name_,
+			p.seed_,
+			(SELECT COUNT(*) FROM tournament_match_ tm
+				WHERE tm.tournament_id_ = p.tournament_id_
+				AND tm.player1_id_ IS NOT NULL AND tm.player2_id_ IS NOT NULL
+				AND tm.winner_id_ = p.user_id_) AS wins_,
+			(SELECT COUNT(*) FROM tournament_match_ tm
+				WHERE tm.tournament_id_ = p.tournament_id_
+				AND (tm.player1_id_ = p.user_id_ OR tm.player2_id_ = p.user_id_)
+				AND tm.winner_id_ <> p.user_id_)
+		FROM tournament_player_ p
+		INNER JOIN user_ u
+			ON u.id_ = p.user_id_
+		WHERE p.tournament_id_ = $1
+		ORDER BY wins_ DESC, p.seed_, p.registered_at_;`
+
+	rows, err := m.db.Query(ctx, sql, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanTournamentPlayer)
+}
+
+// Registers the user for a tournament that has not yet started
+func (m *TournamentModel) Register(ctx context.Context, id, userID int) error {
+	sql := `INSERT INTO tournament_player_ (tournament_id_, user_id_)
+		SELECT id_, $2 FROM tournament_
+		WHERE id_ = $1 AND started_at_ IS NULL;`
+
+	tag, err := m.db.Exec(ctx, sql, id, userID)
+	if err != nil {
+		if pgErrCode(err) == pgerrcode.UniqueViolation {
+			return ErrDuplicateMember
+		}
+
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrTournamentStarted
+	}
+
+	return nil
+}
+
+func (m *TournamentModel) Withdraw(ctx context.Context, id, userID int) error {
+	sql := `DELETE FROM tournament_player_ p
+		USING tournament_ t
+		WHERE t.id_ = p.tournament_id_
+		AND p.tournament_id_ = $1 AND p.user_id_ = $2
+		AND t.started_at_ IS NULL;`
+
+	tag, err := m.db.Exec(ctx, sql, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// Closes registration, seeds the players and generates the schedule or
+// bracket. Players are seeded by their ladder rating for the sport, then by
+// registration order.
+func (m *TournamentModel) Start(ctx context.Context, id, organizerID int) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var format string
+
+	sql := `UPDATE tournament_ SET started_at_ = NOW()
+		WHERE id_ = $1 AND organizer_id_ = $2 AND started_at_ IS NULL
+		RETURNING format_;`
+
+	err = tx.QueryRow(ctx, sql, id, organizerID).Scan(&format)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNoRecord
+		}
+
+		return err
+	}
+
+	sql = `SELECT p.user_id_
+		FROM tournament_player_ p
+		INNER JOIN tournament_ t
+			ON t.id_ = p.tournament_id_
+		LEFT JOIN ladder_ l
+			ON l.sport_id_ = t.sport_id_ AND l.user_id_ = p.user_id_
+		WHERE p.tournament_id_ = $1
+		ORDER BY l.rating_ DESC NULLS LAST, p.registered_at_;`
+
+	rows, err := tx.Query(ctx, sql, id)
+	if err != nil {
+		return err
+	}
+
+	players, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+
+	if len(players) < 2 {
+		return ErrNotEnoughPlayers
+	}
+
+	sql = `UPDATE tournament_player_ SET seed_ = $3
+		WHERE tournament_id_ = $1 AND user_id_ = $2;`
+
+	for i, p := range players {
+		_, err = tx.Exec(ctx, sql, id, p, i+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	var rounds [][]Pairing
+	if format == TournamentSingleElimination {
+		rounds = SingleEliminationBracket(players)
+	} else {
+		rounds = RoundRobinSchedule(players)
+	}
+
+	sql = `INSERT INTO tournament_match_
+		(tournament_id_, round_, slot_, player1_id_, player2_id_, winner_id_)
+		VALUES($1, $2, $3, $4, $5, $6);`
+
+	for r, round := range rounds {
+		for s, p := range round {
+			// Only first round byes are decided up front
+			winner := 0
+			if r == 0 {
+				winner = p.Winner()
+			}
+
+			_, err = tx.Exec(ctx, sql, id, r+1, s+1,
+				nullID(p.Player1), nullID(p.Player2), nullID(winner))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+type TournamentMatch struct {
+	ID          int
+	Round       int
+	Slot        int
+	Player1ID   *int
+	Player1Name *string
+	Player2ID   *int
+	Player2Name *string
+	WinnerID    *int
+	Score       *string
+}
+
+func (m *TournamentMatch) IsBye() bool {
+	return m.WinnerID != nil && (m.Player1ID == nil || m.Player2ID == nil)
+}
+
+func (m *TournamentMatch) IsPending() bool {
+	return m.WinnerID == nil && m.Player1ID != nil && m.Player2ID != nil
+}
+
+func (m *TournamentMatch) IsWonBy(userID *int) bool {
+	return m.WinnerID != nil && userID != nil && *m.WinnerID == *userID
+}
+
+func (m *TournamentMatch) HasPlayer(userID int) bool {
+	return (m.Player1ID != nil && *m.Player1ID == userID) ||
+		(m.Player2ID != nil && *m.Player2ID == userID)
+}
+
+func scanTournamentMatch(row pgx.CollectableRow) (*TournamentMatch, error) {
+	var m TournamentMatch
+	err := row.Scan(
+		&m.ID,
+		&m.Round,
+		&m.Slot,
+		&m.Player1ID,
+		&m.Player1Name,
+		&m.Player2ID,
+		&m.Player2Name,
+		&m.WinnerID,
+		&m.Score)
+
+	return &m, err
+}
+
+func (m *TournamentModel) Matches(ctx context.Context, id int) ([]*TournamentMatch, error) {
+	sql := `SELECT
+			tm.id_,
+			tm.round_,
+			tm.slot_,
+			tm.player1_id_,
+			p1.name_,
+			tm.player2_id_,
+			p2.name_,
+			tm.winner_id_,
+			tm.score_
+		FROM tournament_match_ tm
+		LEFT JOIN user_ p1
+			ON p1.id_ = tm.player1_id_
+		LEFT JOIN user_ p2
+			ON p2.id_ = tm.player2_id_
+		WHERE tm.tournament_id_ = $1
+		ORDER BY tm.round_, tm.slot_;`
+
+	rows, err := m.db.Query(ctx, sql, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanTournamentMatch)
+}
+
+// Records the result of a match. Players in the match and the organizer may
+// enter results. In single elimination the winner advances to the next round.
+func (m *TournamentModel) Result(ctx context.Context, id, matchID, userID, winnerID int, score string) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var format string
+	var round, slot int
+
+	sql := `UPDATE tournament_match_ tm SET winner_id_ = $4, score_ = $5
+		FROM tournament_ t
+		WHERE t.id_ = tm.tournament_id_
+		AND tm.id_ = $2 AND tm.tournament_id_ = $1
+		AND tm.winner_id_ IS NULL
+		AND $4 IN (tm.player1_id_, tm.player2_id_)
+		AND $3 IN (tm.player1_id_, tm.player2_id_, t.organizer_id_)
+		RETURNING t.format_, tm.round_, tm.slot_;`
+
+	err = tx.QueryRow(ctx, sql, id, matchID, userID, winnerID, score).Scan(
+		&format, &round, &slot)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNoRecord
+		}
+
+		return err
+	}
+
+	if format == TournamentSingleElimination {
+		// Slot 2k-1 and 2k feed slot k of the next round
+		column := "player1_id_"
+		if slot%2 == 0 {
+			column = "player2_id_"
+		}
+
+		sql = `UPDATE tournament_match_ SET ` + column + ` = $4
+			WHERE tournament_id_ = $1 AND round_ = $2 AND slot_ = $3;`
+
+		_, err = tx.Exec(ctx, sql, id, round+1, (slot+1)/2, winnerID)
+		if err != nil {
+			return err
+		}
+	}
+
+	sql = `UPDATE tournament_ SET finished_at_ = NOW()
+		WHERE id_ = $1 AND NOT EXISTS(
+			SELECT true FROM tournament_match_
+			WHERE tournament_id_ = $1 AND winner_id_ IS NULL);`
+
+	_, err = tx.Exec(ctx, sql, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func nullID(id int) *int {
+	if id == 0 {
+		return nil
+	}
+
+	return &id
+}
+
+// Two players due to meet. A zero player is a bye in the first round of a
+// bracket, or an undecided winner in later rounds.
+type Pairing struct {
+	Player1 int
+	Player2 int
+}
+
+// Returns the player who advances automatically from a bye
+func (p Pairing) Winner() int {
+	if p.Player1 == 0 || p.Player2 == 0 {
+		return p.Player1 + p.Player2
+	}
+
+	return 0
+}
+
+// Schedules every player against every other player using the circle
+// method. With an odd number of players, one player sits out each round.
+func RoundRobinSchedule(players []int) [][]Pairing {
+	p := append([]int{}, players...)
+	if len(p)%2 != 0 {
+		p = append(p, 0)
+	}
+
+	n := len(p)
+
+	var rounds [][]Pairing
+	for r := 0; r < n-1; r++ {
+		var round []Pairing
+		for i := 0; i < n/2; i++ {
+			a, b := p[i], p[n-1-i]
+			if a != 0 && b != 0 {
+				round = append(round, Pairing{a, b})
+			}
+		}
+		rounds = append(rounds, round)
+
+		// Keep the first player fixed and rotate the rest
+		last := p[n-1]
+		copy(p[2:], p[1:n-1])
+		p[1] = last
+	}
+
+	return rounds
+}
+
+// Builds a bracket for players ordered by seed. The bracket is padded to a
+// power of two with byes, which are given to the top seeds.
+func SingleEliminationBracket(players []int) [][]Pairing {
+	size := 1
+	for size < len(players) {
+		size *= 2
+	}
+
+	// Standard seeding keeps the top seeds apart until the later rounds
+	seeds := []int{1}
+	for len(seeds) < size {
+		n := len(seeds) * 2
+		next := make([]int, 0, n)
+		for _, s := range seeds {
+			next = append(next, s, n+1-s)
+		}
+		seeds = next
+	}
+
+	player := func(seed int) int {
+		if seed > len(players) {
+			return 0
+		}
+
+		return players[seed-1]
+	}
+
+	var first []Pairing
+	for i := 0; i < size; i += 2 {
+		first = append(first, Pairing{player(seeds[i]), player(seeds[i+1])})
+	}
+
+	rounds := [][]Pairing{first}
+	for n := size / 4; n >= 1; n /= 2 {
+		round := make([]Pairing, n)
+		prev := rounds[len(rounds)-1]
+
+		// Players with a bye in the first round advance straight away
+		if len(rounds) == 1 {
+			for i := range round {
+				round[i] = Pairing{prev[2*i].Winner(), prev[2*i+1].Winner()}
+			}
+		}
+
+		rounds = append(rounds, round)
+	}
+
+	return rounds
+}
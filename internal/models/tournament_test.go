@@ -0,0 +1,89 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/micahco/racket-connections/internal/assert"
+)
+
+func TestRoundRobinSchedule(t *testing.T) {
+	for _, n := range []int{2, 3, 4, 5, 8} {
+		players := make([]int, n)
+		for i := range players {
+			players[i] = i + 1
+		}
+
+		met := map[Pairing]int{}
+		for _, round := range RoundRobinSchedule(players) {
+			seen := map[int]bool{}
+			for _, p := range round {
+				assert.Equal(t, seen[p.Player1] || seen[p.Player2], false)
+				seen[p.Player1], seen[p.Player2] = true, true
+
+				a, b := min(p.Player1, p.Player2), max(p.Player1, p.Player2)
+				met[Pairing{a, b}]++
+			}
+		}
+
+		// Every pair of players meets exactly once
+		assert.Equal(t, len(met), n*(n-1)/2)
+		for _, count := range met {
+			assert.Equal(t, count, 1)
+		}
+	}
+}
+
+func TestSingleEliminationBracket(t *testing.T) {
+	tests := []struct {
+		name    string
+		players []int
+		want    [][]Pairing
+	}{
+		{
+			name:    "Two players",
+			players: []int{10, 20},
+			want:    [][]Pairing{{{10, 20}}},
+		},
+		{
+			name:    "Three players",
+			players: []int{10, 20, 30},
+			want: [][]Pairing{
+				{{10, 0}, {20, 30}},
+				{{10, 0}},
+			},
+		},
+		{
+			name:    "Five players",
+			players: []int{1, 2, 3, 4, 5},
+			want: [][]Pairing{
+				{{1, 0}, {4, 5}, {2, 0}, {3, 0}},
+				{{1, 0}, {2, 3}},
+				{{0, 0}},
+			},
+		},
+		{
+			name:    "Eight players",
+			players: []int{1, 2, 3, 4, 5, 6, 7, 8},
+			want: [][]Pairing{
+				{{1, 8}, {4, 5}, {2, 7}, {3, 6}},
+				{{0, 0}, {0, 0}},
+				{{0, 0}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rounds := SingleEliminationBracket(tt.players)
+			assert.Equal(t, len(rounds), len(tt.want))
+
+			for r := range tt.want {
+				assert.Equal(t, len(rounds[r]), len(tt.want[r]))
+
+				for s := range tt.want[r] {
+					assert.Equal(t, rounds[r][s], tt.want[r][s])
+				}
+			}
+		})
+	}
+}
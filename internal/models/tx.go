@@ -0,0 +1,34 @@
+package models
+
+import (
+	"context"
+
+	"github.com/micahco/racket-connections/internal/crypto"
+)
+
+type TxModelInterface interface {
+	Do(ctx context.Context, fn func(tx Models) error) error
+}
+
+type TxModel struct {
+	db     dbtx
+	hasher crypto.PasswordHasher
+}
+
+// Runs fn with models that share a single transaction. The transaction is
+// committed if fn returns nil and rolled back otherwise. Transactions begun
+// by the models inside fn, or by nested calls to Do, use savepoints.
+func (m *TxModel) Do(ctx context.Context, fn func(tx Models) error) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = fn(newModels(tx, m.hasher))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
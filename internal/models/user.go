@@ -3,23 +3,36 @@ package models
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/micahco/racket-connections/internal/crypto"
 )
 
+type UserModelInterface interface {
+	Insert(ctx context.Context, name, email, password string) (int, error)
+	Authenticate(ctx context.Context, email, password string) (int, error)
+	Exists(ctx context.Context, id int) (bool, error)
+	ExistsEmail(ctx context.Context, email string) (bool, error)
+	Count(ctx context.Context) (int, error)
+	GetIDByEmail(ctx context.Context, email string) (int, error)
+	UpdatePassword(ctx context.Context, email, password string) error
+	GetProfile(ctx context.Context, id int) (*UserProfile, error)
+	Delete(ctx context.Context, id int) error
+}
+
 type UserModel struct {
-	pool *pgxpool.Pool
+	db     dbtx
+	hasher crypto.PasswordHasher
 }
 
 type User struct {
 	ID           int
 	Name         string
 	Email        string
-	PasswordHash []byte
+	PasswordHash string
 	CreatedAt    time.Time
 }
 
@@ -35,8 +48,8 @@ func scanUser(row pgx.CollectableRow) (*User, error) {
 	return &u, err
 }
 
-func (m *UserModel) Insert(name, email, password string) (int, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func (m *UserModel) Insert(ctx context.Context, name, email, password string) (int, error) {
+	hash, err := m.hasher.Hash(password)
 	if err != nil {
 		return 0, err
 	}
@@ -47,7 +60,7 @@ func (m *UserModel) Insert(name, email, password string) (int, error) {
 		(name_, email_, password_hash_)
 		VALUES($1, $2, $3) RETURNING id_;`
 
-	err = m.pool.QueryRow(context.Background(), sql,
+	err = m.db.QueryRow(ctx, sql,
 		name, email, hash).Scan(&id)
 
 	if pgErrCode(err) == pgerrcode.UniqueViolation {
@@ -57,10 +70,13 @@ func (m *UserModel) Insert(name, email, password string) (int, error) {
 	return id, err
 }
 
-func (m *UserModel) Authenticate(email, password string) (int, error) {
+// Returns the user's ID if the password matches. A failure to upgrade the
+// stored hash is returned as ErrRehash alongside the ID, since the password
+// was still correct.
+func (m *UserModel) Authenticate(ctx context.Context, email, password string) (int, error) {
 	sql := "SELECT * FROM user_ WHERE email_ = $1;"
 
-	rows, err := m.pool.Query(context.Background(), sql, email)
+	rows, err := m.db.Query(ctx, sql, email)
 	if err != nil {
 		return 0, err
 	}
@@ -74,47 +90,86 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 		}
 	}
 
-	err = bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password))
+	err = crypto.ComparePassword(user.PasswordHash, password)
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		if errors.Is(err, crypto.ErrMismatchedPassword) {
 			return -0, ErrInvalidCredentials
 		} else {
 			return 0, err
 		}
 	}
 
+	// Upgrade hashes made with an older algorithm or weaker parameters now
+	// that the plaintext password is known
+	if m.hasher.NeedsRehash(user.PasswordHash) {
+		hash, err := m.hasher.Hash(password)
+		if err != nil {
+			return user.ID, fmt.Errorf("%w: %w", ErrRehash, err)
+		}
+
+		sql = "UPDATE user_ SET password_hash_ = $1 WHERE id_ = $2;"
+
+		_, err = m.db.Exec(ctx, sql, hash, user.ID)
+		if err != nil {
+			return user.ID, fmt.Errorf("%w: %w", ErrRehash, err)
+		}
+	}
+
 	return user.ID, nil
 }
 
-func (m *UserModel) Exists(id int) (bool, error) {
+func (m *UserModel) Exists(ctx context.Context, id int) (bool, error) {
 	var exists bool
 
 	sql := "SELECT EXISTS(SELECT true FROM user_ WHERE id_ = $1);"
 
-	err := m.pool.QueryRow(context.Background(), sql, id).Scan(&exists)
+	err := m.db.QueryRow(ctx, sql, id).Scan(&exists)
 
 	return exists, err
 }
 
-func (m *UserModel) ExistsEmail(email string) (bool, error) {
+func (m *UserModel) ExistsEmail(ctx context.Context, email string) (bool, error) {
 	var exists bool
 
 	sql := "SELECT EXISTS(SELECT true FROM user_ WHERE email_ = $1);"
 
-	err := m.pool.QueryRow(context.Background(), sql, email).Scan(&exists)
+	err := m.db.QueryRow(ctx, sql, email).Scan(&exists)
 
 	return exists, err
 }
 
-func (m *UserModel) UpdatePassword(email, password string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func (m *UserModel) Count(ctx context.Context) (int, error) {
+	var n int
+
+	sql := "SELECT COUNT(*) FROM user_;"
+
+	err := m.db.QueryRow(ctx, sql).Scan(&n)
+
+	return n, err
+}
+
+func (m *UserModel) GetIDByEmail(ctx context.Context, email string) (int, error) {
+	var id int
+
+	sql := "SELECT id_ FROM user_ WHERE email_ = $1;"
+
+	err := m.db.QueryRow(ctx, sql, email).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrNoRecord
+	}
+
+	return id, err
+}
+
+func (m *UserModel) UpdatePassword(ctx context.Context, email, password string) error {
+	hash, err := m.hasher.Hash(password)
 	if err != nil {
 		return err
 	}
 
 	sql := "UPDATE user_ SET password_hash_ = $1 WHERE email_ = $2;"
 
-	_, err = m.pool.Exec(context.Background(), sql, hash, email)
+	_, err = m.db.Exec(ctx, sql, hash, email)
 
 	return err
 }
@@ -131,10 +186,10 @@ func scanUserProfile(row pgx.CollectableRow) (*UserProfile, error) {
 	return &u, err
 }
 
-func (m *UserModel) GetProfile(id int) (*UserProfile, error) {
+func (m *UserModel) GetProfile(ctx context.Context, id int) (*UserProfile, error) {
 	sql := "SELECT name_, email_ FROM user_ WHERE id_ = $1;"
 
-	rows, err := m.pool.Query(context.Background(), sql, id)
+	rows, err := m.db.Query(ctx, sql, id)
 	if err != nil {
 		return nil, err
 	}
@@ -142,10 +197,83 @@ func (m *UserModel) GetProfile(id int) (*UserProfile, error) {
 	return pgx.CollectOneRow(rows, scanUserProfile)
 }
 
-func (m *UserModel) Delete(id int) error {
-	sql := "DELETE FROM user_ WHERE id_ = $1;"
+// Deletes the user and all of their data. Most tables cascade from user_, but
+// sessions and the tables keyed by email are deleted explicitly. Results and
+// tournaments shared with other players are kept with the user set to NULL.
+func (m *UserModel) Delete(ctx context.Context, id int) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = deleteUser(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func deleteUser(ctx context.Context, tx *dbTx, id int) error {
+	var email string
+
+	sql := "SELECT email_ FROM user_ WHERE id_ = $1 FOR UPDATE;"
+
+	err := tx.QueryRow(ctx, sql, id).Scan(&email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNoRecord
+		}
+
+		return err
+	}
+
+	sql = `DELETE FROM sessions WHERE token IN (
+		SELECT token_ FROM session_info_ WHERE user_id_ = $1);`
+
+	_, err = tx.Exec(ctx, sql, id)
+	if err != nil {
+		return err
+	}
+
+	for _, sql := range []string{
+		"DELETE FROM auth_attempt_ WHERE email_ = $1;",
+		"DELETE FROM lockout_ WHERE email_ = $1;",
+		"DELETE FROM verification_ WHERE email_ = $1;",
+		"DELETE FROM email_ WHERE recipient_ = $1;",
+	} {
+		_, err = tx.Exec(ctx, sql, email)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Confirmed results stay with the other player while anything still
+	// pending goes with the account. The user's ladders are locked, as in
+	// Leave, before closing the gaps they leave behind.
+	for _, sql := range []string{
+		`SELECT id_ FROM sport_ WHERE id_ IN (
+			SELECT sport_id_ FROM ladder_ WHERE user_id_ = $1)
+		ORDER BY id_ FOR NO KEY UPDATE;`,
+		`WITH d AS (DELETE FROM ladder_ WHERE user_id_ = $1
+			RETURNING sport_id_, position_)
+		UPDATE ladder_ l SET position_ = l.position_ - 1
+		FROM d WHERE l.sport_id_ = d.sport_id_ AND l.position_ > d.position_;`,
+		`DELETE FROM challenge_ WHERE confirmed_at_ IS NULL
+		AND (challenger_id_ = $1 OR opponent_id_ = $1);`,
+		`DELETE FROM match_ WHERE confirmed_at_ IS NULL
+		AND (reporter_id_ = $1 OR opponent_id_ = $1);`,
+	} {
+		_, err = tx.Exec(ctx, sql, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	sql = "DELETE FROM user_ WHERE id_ = $1;"
 
-	_, err := m.pool.Exec(context.Background(), sql, id)
+	_, err = tx.Exec(ctx, sql, id)
 
 	return err
 }
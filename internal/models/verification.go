@@ -6,22 +6,41 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/micahco/racket-connections/internal/crypto"
 )
 
 const (
 	expiration = time.Hour * 24
 )
 
+// What a verification token may be used for. A token issued for one purpose
+// is never accepted for another.
+const (
+	PurposeSignup = "signup"
+	PurposeReset  = "reset"
+	PurposeExport = "export"
+)
+
+type VerificationModelInterface interface {
+	Insert(ctx context.Context, token, purpose, email string) error
+	Latest(ctx context.Context, purpose, email string) (*Verification, error)
+	Consume(ctx context.Context, token, purpose, email string) error
+	Purge(ctx context.Context) (int64, error)
+}
+
+// Tokens emailed to users to prove they own an address. Only the SHA-256 hash
+// of each token is stored.
 type VerificationModel struct {
-	pool *pgxpool.Pool
+	db dbtx
 }
 
 type Verification struct {
-	Token     string
-	Email     string
-	Expiry    time.Time
-	CreatedAt time.Time
+	TokenHash  string
+	Purpose    string
+	Email      string
+	Expiry     time.Time
+	ConsumedAt *time.Time
+	CreatedAt  time.Time
 }
 
 func (v *Verification) IsExpired() bool {
@@ -31,30 +50,36 @@ func (v *Verification) IsExpired() bool {
 func scanVerification(row pgx.CollectableRow) (*Verification, error) {
 	var v Verification
 	err := row.Scan(
-		&v.Token,
+		&v.TokenHash,
+		&v.Purpose,
 		&v.Email,
 		&v.Expiry,
+		&v.ConsumedAt,
 		&v.CreatedAt)
 
 	return &v, err
 }
 
-func (m *VerificationModel) Insert(token, email string) error {
+func (m *VerificationModel) Insert(ctx context.Context, token, purpose, email string) error {
 	expiry := time.Now().Add(expiration)
 
 	sql := `INSERT INTO verification_
-		(token_, email_, expiry_)
-		VALUES($1, $2, $3);`
+		(token_hash_, purpose_, email_, expiry_)
+		VALUES($1, $2, $3, $4);`
 
-	_, err := m.pool.Exec(context.Background(), sql, token, email, expiry)
+	_, err := m.db.Exec(ctx, sql,
+		crypto.HashToken(token), purpose, email, expiry)
 
 	return err
 }
 
-func (m *VerificationModel) Get(email string) (*Verification, error) {
-	sql := "SELECT * FROM verification_ WHERE email_ = $1;"
+// Returns the most recently issued token for the purpose and email
+func (m *VerificationModel) Latest(ctx context.Context, purpose, email string) (*Verification, error) {
+	sql := `SELECT * FROM verification_
+		WHERE purpose_ = $1 AND email_ = $2
+		ORDER BY created_at_ DESC LIMIT 1;`
 
-	rows, err := m.pool.Query(context.Background(), sql, email)
+	rows, err := m.db.Query(ctx, sql, purpose, email)
 	if err != nil {
 		return nil, err
 	}
@@ -67,31 +92,61 @@ func (m *VerificationModel) Get(email string) (*Verification, error) {
 	return v, err
 }
 
-func (m *VerificationModel) Verify(token, email string) error {
-	sql := `SELECT * FROM verification_ 
-		WHERE token_ = $1 AND email_ = $2;`
-
-	rows, err := m.pool.Query(context.Background(), sql, token, email)
+// Marks the token as used. Once a valid token has been consumed, any others
+// outstanding for the same purpose and email are consumed with it. Returns
+// ErrNoRecord if the token does not exist or was already used.
+func (m *VerificationModel) Consume(ctx context.Context, token, purpose, email string) error {
+	tx, err := m.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback(ctx)
 
-	v, err := pgx.CollectOneRow(rows, scanVerification)
-	if errors.Is(err, pgx.ErrNoRows) {
-		return ErrNoRecord
+	var expiry time.Time
+
+	sql := `UPDATE verification_ SET consumed_at_ = NOW()
+		WHERE token_hash_ = $1 AND purpose_ = $2 AND email_ = $3
+		AND consumed_at_ IS NULL
+		RETURNING expiry_;`
+
+	err = tx.QueryRow(ctx, sql, crypto.HashToken(token), purpose, email).Scan(&expiry)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNoRecord
+		}
+
+		return err
 	}
 
-	if v.IsExpired() {
+	if time.Now().After(expiry) {
+		// Keep the expired token marked as used
+		err = tx.Commit(ctx)
+		if err != nil {
+			return err
+		}
+
 		return ErrExpiredVerification
 	}
 
-	return nil
+	sql = `UPDATE verification_ SET consumed_at_ = NOW()
+		WHERE purpose_ = $1 AND email_ = $2 AND consumed_at_ IS NULL;`
+
+	_, err = tx.Exec(ctx, sql, purpose, email)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
-func (m *VerificationModel) Purge(email string) error {
-	sql := "DELETE FROM verification_ WHERE email_ = $1;"
+// Deletes expired tokens and returns how many were removed
+func (m *VerificationModel) Purge(ctx context.Context) (int64, error) {
+	sql := "DELETE FROM verification_ WHERE expiry_ < NOW();"
 
-	_, err := m.pool.Exec(context.Background(), sql, email)
+	tag, err := m.db.Exec(ctx, sql)
+	if err != nil {
+		return 0, err
+	}
 
-	return err
+	return tag.RowsAffected(), nil
 }
@@ -0,0 +1,90 @@
+//go:build ignore
+
+// Builds breached.txt.gz from a list of common passwords, one per line and
+// most common first, such as the top 10,000 list from SecLists:
+//
+//	go run breached_gen.go -n 10000 10k-most-common.txt
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+func main() {
+	n := flag.Int("n", 10000, "Number of passwords to keep")
+	out := flag.String("o", "breached.txt.gz", "Output file")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		log.Fatal("usage: go run breached_gen.go [-n count] [-o file] list.txt")
+	}
+
+	in, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer in.Close()
+
+	seen := make(map[string]bool)
+	var passwords []string
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() && len(passwords) < *n {
+		p := strings.TrimRight(scanner.Text(), "\r")
+		if p == "" || seen[p] {
+			continue
+		}
+
+		seen[p] = true
+		passwords = append(passwords, p)
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	sort.Strings(passwords)
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewWriterLevel(f, gzip.BestCompression)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := bufio.NewWriter(gz)
+
+	var prev string
+	for _, p := range passwords {
+		shared := 0
+		for shared < len(prev) && shared < len(p) && prev[shared] == p[shared] {
+			shared++
+		}
+
+		fmt.Fprintf(w, "%d %s\n", shared, p[shared:])
+		prev = p
+	}
+
+	err = w.Flush()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = gz.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("wrote %d passwords to %s", len(passwords), *out)
+}
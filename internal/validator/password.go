@@ -0,0 +1,214 @@
+package validator
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha1"
+	"embed"
+	"encoding/hex"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// The most common passwords found in public data breaches, sorted and
+// front coded: each line holds the length of the prefix shared with the
+// previous entry, a space, then the rest of the entry. Regenerate it with
+// breached_gen.go.
+//
+//go:embed breached.txt.gz
+var breachedFS embed.FS
+
+// Minimum estimated entropy, in bits, of an acceptable password
+const minPasswordBits = 40
+
+var (
+	breachedOnce sync.Once
+	// Sorted hash suffixes keyed by the first five characters of the hash
+	breachedRanges map[string][]string
+)
+
+func loadBreached() {
+	breachedRanges = make(map[string][]string)
+
+	f, err := breachedFS.Open("breached.txt.gz")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		panic(err)
+	}
+	defer gz.Close()
+
+	var prev string
+
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		n, rest, found := strings.Cut(scanner.Text(), " ")
+		shared, err := strconv.Atoi(n)
+		if !found || err != nil || shared > len(prev) {
+			panic("validator: malformed breached password list")
+		}
+
+		password := prev[:shared] + rest
+		prev = password
+
+		sum := sha1.Sum([]byte(password))
+		h := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+		breachedRanges[h[:5]] = append(breachedRanges[h[:5]], h[5:])
+	}
+
+	if err := scanner.Err(); err != nil {
+		panic(err)
+	}
+
+	for _, suffixes := range breachedRanges {
+		sort.Strings(suffixes)
+	}
+}
+
+// Looks up the password the same way as a k-anonymity range query: only the
+// hash prefix selects a range, which is then searched for the suffix.
+func inBreachedList(password string) bool {
+	breachedOnce.Do(loadBreached)
+
+	sum := sha1.Sum([]byte(password))
+	h := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes := breachedRanges[h[:5]]
+	i := sort.SearchStrings(suffixes, h[5:])
+
+	return i < len(suffixes) && suffixes[i] == h[5:]
+}
+
+var leetReplacer = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s")
+
+// Reports whether the password, or a simple variation of a common password
+// such as "P@ssword2024!", has appeared in a data breach.
+func Breached(password string) bool {
+	lower := strings.ToLower(password)
+	if inBreachedList(password) || inBreachedList(lower) {
+		return true
+	}
+
+	// Strip the digits and symbols commonly appended to a word
+	base := strings.TrimRightFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	if len(base) >= 4 {
+		return inBreachedList(base) || inBreachedList(leetReplacer.Replace(base))
+	}
+
+	return false
+}
+
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+	"abcdefghijklmnopqrstuvwxyz",
+}
+
+// Reports whether b follows a on a keyboard row or in the alphabet, in
+// either direction
+func adjacent(a, b rune) bool {
+	for _, row := range keyboardRows {
+		i := strings.IndexRune(row, a)
+		j := strings.IndexRune(row, b)
+		if i >= 0 && j >= 0 && (j-i == 1 || i-j == 1) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Estimates the entropy of the password in bits. Characters that repeat the
+// previous one or continue a sequence such as "abc", "123" or "qwe" count
+// for little.
+func PasswordBits(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	var length float64
+	var prev rune
+
+	for i, r := range []rune(password) {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+
+		l := unicode.ToLower(r)
+		if i > 0 && (l == prev || adjacent(prev, l)) {
+			length += 0.25
+		} else {
+			length++
+		}
+		prev = l
+	}
+
+	var charset float64
+	if lower {
+		charset += 26
+	}
+	if upper {
+		charset += 26
+	}
+	if digit {
+		charset += 10
+	}
+	if symbol {
+		charset += 33
+	}
+	if other {
+		charset += 100
+	}
+
+	if charset == 0 {
+		return 0
+	}
+
+	return length * math.Log2(charset)
+}
+
+// Returns why the password is unsuitable, or "" if it is acceptable. Inputs
+// such as the user's name and email must not appear in the password.
+func PasswordProblem(password string, userInputs ...string) string {
+	if Breached(password) {
+		return "this password has appeared in a data breach and is too common, please choose another"
+	}
+
+	lower := strings.ToLower(password)
+	for _, input := range userInputs {
+		for _, part := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len(part) >= 4 && strings.Contains(lower, part) {
+				return "must not contain your name or email address"
+			}
+		}
+	}
+
+	if PasswordBits(password) < minPasswordBits {
+		return "too easy to guess, try a longer password or a mix of uppercase letters, numbers and symbols, and avoid repeated characters and sequences like abc or 1234"
+	}
+
+	return ""
+}
@@ -0,0 +1,109 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/micahco/racket-connections/internal/assert"
+)
+
+func TestBreached(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{
+			name:     "Common",
+			password: "password",
+			want:     true,
+		},
+		{
+			name:     "Capitalized",
+			password: "PASSWORD",
+			want:     true,
+		},
+		{
+			name:     "Appended digits",
+			password: "football2024!",
+			want:     true,
+		},
+		{
+			name:     "Leet",
+			password: "p455w0rd99",
+			want:     true,
+		},
+		{
+			name:     "Numeric",
+			password: "12345678",
+			want:     true,
+		},
+		{
+			name:     "Uncommon",
+			password: "correct horse battery staple",
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, Breached(tt.password), tt.want)
+		})
+	}
+}
+
+func TestPasswordProblem(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		inputs   []string
+		ok       bool
+	}{
+		{
+			name:     "Strong",
+			password: "Tr0mbone-Glacier",
+			ok:       true,
+		},
+		{
+			name:     "Long lowercase",
+			password: "plumtrainvelvet",
+			ok:       true,
+		},
+		{
+			name:     "Breached",
+			password: "iloveyou1",
+			ok:       false,
+		},
+		{
+			name:     "Sequence",
+			password: "abcdefghijk",
+			ok:       false,
+		},
+		{
+			name:     "Keyboard",
+			password: "zxcvbnm,./",
+			ok:       false,
+		},
+		{
+			name:     "Repeated",
+			password: "aaaaaaaaaaaa",
+			ok:       false,
+		},
+		{
+			name:     "Short lowercase",
+			password: "kqzjwmvp",
+			ok:       false,
+		},
+		{
+			name:     "Contains email",
+			password: "beaverb-Tr0mbone",
+			inputs:   []string{"beaverb@oregonstate.edu", "Benny Beaver"},
+			ok:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, PasswordProblem(tt.password, tt.inputs...) == "", tt.ok)
+		})
+	}
+}
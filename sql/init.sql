@@ -34,6 +34,12 @@ CREATE TABLE IF NOT EXISTS time_of_day_ (
     abbrev_ VARCHAR(3) UNIQUE
 );
 
+CREATE TABLE IF NOT EXISTS post_format_ (
+    id_ SERIAL PRIMARY KEY,
+    name_ TEXT UNIQUE NOT NULL,
+    max_players_needed_ INT NOT NULL
+);
+
 CREATE TABLE IF NOT EXISTS contact_method_ (
     id_ SERIAL PRIMARY KEY,
     name_ TEXT UNIQUE
@@ -43,7 +49,7 @@ CREATE TABLE IF NOT EXISTS user_ (
     id_ BIGSERIAL PRIMARY KEY,
     name_ TEXT NOT NULL,
     email_ CITEXT UNIQUE NOT NULL,
-    password_hash_ CHAR(60) NOT NULL,
+    password_hash_ TEXT NOT NULL,
     created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
@@ -52,7 +58,7 @@ CREATE TABLE IF NOT EXISTS timeslot_ (
     day_id_ INT NOT NULL,
     time_id_ INT NOT NULL,
     PRIMARY KEY (user_id_, day_id_, time_id_),
-    FOREIGN KEY (user_id_) REFERENCES user_(id_),
+    FOREIGN KEY (user_id_) REFERENCES user_(id_) ON DELETE CASCADE,
     FOREIGN KEY (day_id_) REFERENCES day_of_week_(id_),
     FOREIGN KEY (time_id_) REFERENCES time_of_day_(id_)
 );
@@ -73,18 +79,244 @@ CREATE TABLE IF NOT EXISTS post_ (
     user_id_ INT NOT NULL,
     sport_id_ INT NOT NULL,
     skill_level_id_ INT NOT NULL,
+    format_id_ INT NOT NULL DEFAULT 1,
+    players_needed_ INT NOT NULL DEFAULT 1,
+    closed_at_ TIMESTAMPTZ,
     FOREIGN KEY (user_id_) REFERENCES user_(id_) ON DELETE CASCADE,
     FOREIGN KEY (sport_id_) REFERENCES sport_(id_) ON DELETE CASCADE,
-    FOREIGN KEY (skill_level_id_) REFERENCES skill_level_(id_) ON DELETE CASCADE
+    FOREIGN KEY (skill_level_id_) REFERENCES skill_level_(id_) ON DELETE CASCADE,
+    FOREIGN KEY (format_id_) REFERENCES post_format_(id_) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS roster_ (
+    post_id_ INT NOT NULL,
+    user_id_ INT NOT NULL,
+    joined_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (post_id_, user_id_),
+    FOREIGN KEY (post_id_) REFERENCES post_(id_) ON DELETE CASCADE,
+    FOREIGN KEY (user_id_) REFERENCES user_(id_) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS ladder_ (
+    sport_id_ INT NOT NULL,
+    user_id_ INT NOT NULL,
+    position_ INT NOT NULL,
+    rating_ INT NOT NULL DEFAULT 1200,
+    joined_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (sport_id_, user_id_),
+    -- Deferred so positions can be shifted within a transaction
+    UNIQUE (sport_id_, position_) DEFERRABLE INITIALLY DEFERRED,
+    FOREIGN KEY (sport_id_) REFERENCES sport_(id_) ON DELETE CASCADE,
+    FOREIGN KEY (user_id_) REFERENCES user_(id_) ON DELETE CASCADE
+);
+
+-- Results are shared with the other player, so a deleted account leaves
+-- NULL in its place rather than taking the history with it
+CREATE TABLE IF NOT EXISTS challenge_ (
+    id_ BIGSERIAL PRIMARY KEY,
+    sport_id_ INT NOT NULL,
+    challenger_id_ INT,
+    opponent_id_ INT,
+    winner_id_ INT,
+    score_ TEXT,
+    reported_by_id_ INT,
+    reported_at_ TIMESTAMPTZ,
+    confirmed_at_ TIMESTAMPTZ,
+    expires_at_ TIMESTAMPTZ NOT NULL,
+    created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (sport_id_) REFERENCES sport_(id_) ON DELETE CASCADE,
+    FOREIGN KEY (challenger_id_) REFERENCES user_(id_) ON DELETE SET NULL,
+    FOREIGN KEY (opponent_id_) REFERENCES user_(id_) ON DELETE SET NULL,
+    FOREIGN KEY (winner_id_) REFERENCES user_(id_) ON DELETE SET NULL,
+    FOREIGN KEY (reported_by_id_) REFERENCES user_(id_) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS match_ (
+    id_ BIGSERIAL PRIMARY KEY,
+    sport_id_ INT NOT NULL,
+    reporter_id_ INT,
+    opponent_id_ INT,
+    winner_id_ INT,
+    played_on_ DATE NOT NULL,
+    confirmed_at_ TIMESTAMPTZ,
+    created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (reporter_id_ <> opponent_id_),
+    FOREIGN KEY (sport_id_) REFERENCES sport_(id_) ON DELETE CASCADE,
+    FOREIGN KEY (reporter_id_) REFERENCES user_(id_) ON DELETE SET NULL,
+    FOREIGN KEY (opponent_id_) REFERENCES user_(id_) ON DELETE SET NULL,
+    FOREIGN KEY (winner_id_) REFERENCES user_(id_) ON DELETE SET NULL
+);
+
+-- Scores are stored from the reporting player's perspective
+CREATE TABLE IF NOT EXISTS match_set_ (
+    match_id_ BIGINT NOT NULL,
+    number_ INT NOT NULL,
+    reporter_score_ INT NOT NULL,
+    opponent_score_ INT NOT NULL,
+    PRIMARY KEY (match_id_, number_),
+    FOREIGN KEY (match_id_) REFERENCES match_(id_) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS tournament_ (
+    id_ BIGSERIAL PRIMARY KEY,
+    name_ TEXT NOT NULL,
+    format_ TEXT NOT NULL CHECK (format_ IN ('round robin', 'single elimination')),
+    sport_id_ INT NOT NULL,
+    organizer_id_ INT,
+    started_at_ TIMESTAMPTZ,
+    finished_at_ TIMESTAMPTZ,
+    created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (sport_id_) REFERENCES sport_(id_) ON DELETE CASCADE,
+    FOREIGN KEY (organizer_id_) REFERENCES user_(id_) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS tournament_player_ (
+    tournament_id_ INT NOT NULL,
+    user_id_ INT NOT NULL,
+    seed_ INT,
+    registered_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tournament_id_, user_id_),
+    FOREIGN KEY (tournament_id_) REFERENCES tournament_(id_) ON DELETE CASCADE,
+    FOREIGN KEY (user_id_) REFERENCES user_(id_) ON DELETE CASCADE
+);
+
+-- A NULL player in a single elimination match is a bye, a slot waiting on
+-- the winner of an earlier round or a deleted account
+CREATE TABLE IF NOT EXISTS tournament_match_ (
+    id_ BIGSERIAL PRIMARY KEY,
+    tournament_id_ INT NOT NULL,
+    round_ INT NOT NULL,
+    slot_ INT NOT NULL,
+    player1_id_ INT,
+    player2_id_ INT,
+    winner_id_ INT,
+    score_ TEXT,
+    UNIQUE (tournament_id_, round_, slot_),
+    FOREIGN KEY (tournament_id_) REFERENCES tournament_(id_) ON DELETE CASCADE,
+    FOREIGN KEY (player1_id_) REFERENCES user_(id_) ON DELETE SET NULL,
+    FOREIGN KEY (player2_id_) REFERENCES user_(id_) ON DELETE SET NULL,
+    FOREIGN KEY (winner_id_) REFERENCES user_(id_) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS totp_ (
+    user_id_ INT NOT NULL PRIMARY KEY,
+    secret_ VARCHAR(64) NOT NULL,
+    last_step_ BIGINT NOT NULL DEFAULT 0,
+    created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (user_id_) REFERENCES user_(id_) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS recovery_code_ (
+    id_ BIGSERIAL PRIMARY KEY,
+    user_id_ INT NOT NULL,
+    code_hash_ CHAR(64) NOT NULL,
+    used_at_ TIMESTAMPTZ,
+    UNIQUE (user_id_, code_hash_),
+    FOREIGN KEY (user_id_) REFERENCES user_(id_) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS login_token_ (
+    token_hash_ CHAR(64) NOT NULL PRIMARY KEY,
+    user_id_ INT NOT NULL,
+    expiry_ TIMESTAMPTZ NOT NULL,
+    created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (user_id_) REFERENCES user_(id_) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS identity_ (
+    issuer_ VARCHAR(255) NOT NULL,
+    subject_ VARCHAR(255) NOT NULL,
+    user_id_ INT NOT NULL,
+    created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (issuer_, subject_),
+    FOREIGN KEY (user_id_) REFERENCES user_(id_) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS session_info_ (
+    token_ TEXT NOT NULL PRIMARY KEY,
+    user_id_ INT NOT NULL,
+    ip_ VARCHAR(45) NOT NULL,
+    user_agent_ TEXT NOT NULL,
+    created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (user_id_) REFERENCES user_(id_) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS auth_attempt_ (
+    id_ BIGSERIAL PRIMARY KEY,
+    action_ VARCHAR(20) NOT NULL,
+    ip_ VARCHAR(45) NOT NULL,
+    email_ CITEXT NOT NULL,
+    failed_ BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS auth_attempt_ip_idx_ ON auth_attempt_ (action_, ip_, created_at_);
+CREATE INDEX IF NOT EXISTS auth_attempt_email_idx_ ON auth_attempt_ (action_, email_, created_at_);
+
+CREATE TABLE IF NOT EXISTS lockout_ (
+    email_ CITEXT NOT NULL PRIMARY KEY,
+    until_ TIMESTAMPTZ NOT NULL,
+    created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
+-- Tokens used to be stored in plaintext without a purpose. They only live
+-- for a day, so a table in the old layout is dropped and recreated.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+        WHERE table_schema = current_schema()
+        AND table_name = 'verification_' AND column_name = 'token_') THEN
+        DROP TABLE verification_;
+    END IF;
+END $$;
+
 CREATE TABLE IF NOT EXISTS verification_ (
-    token_ VARCHAR(100) NOT NULL PRIMARY KEY,
+    token_hash_ CHAR(64) NOT NULL PRIMARY KEY,
+    purpose_ VARCHAR(20) NOT NULL,
     email_ CITEXT NOT NULL,
     expiry_ TIMESTAMPTZ NOT NULL,
+    consumed_at_ TIMESTAMPTZ,
+    created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS verification_email_idx_ ON verification_ (purpose_, email_, created_at_);
+
+-- Grant access with: INSERT INTO admin_ (user_id_) VALUES (<id>);
+CREATE TABLE IF NOT EXISTS admin_ (
+    user_id_ INT NOT NULL PRIMARY KEY,
+    created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (user_id_) REFERENCES user_(id_) ON DELETE CASCADE
+);
+
+-- Accounts are purged after purge_at_ unless the user logs in before then
+CREATE TABLE IF NOT EXISTS deletion_ (
+    user_id_ INT NOT NULL PRIMARY KEY,
+    requested_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    purge_at_ TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_id_) REFERENCES user_(id_) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS deletion_purge_idx_ ON deletion_ (purge_at_);
+
+CREATE TABLE IF NOT EXISTS email_ (
+    id_ BIGSERIAL PRIMARY KEY,
+    recipient_ CITEXT NOT NULL,
+    template_ VARCHAR(255) NOT NULL,
+    subject_ TEXT NOT NULL,
+    body_ TEXT NOT NULL,
+    html_body_ TEXT,
+    status_ VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts_ INT NOT NULL DEFAULT 0,
+    next_attempt_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error_ TEXT,
+    message_id_ VARCHAR(255),
+    sent_at_ TIMESTAMPTZ,
     created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
+CREATE INDEX IF NOT EXISTS email_due_idx_ ON email_ (next_attempt_at_) WHERE status_ = 'pending';
+
 /*
  * DATA
  */
@@ -123,6 +355,13 @@ VALUES
     ('squash')
 ON CONFLICT (name_) DO NOTHING;
 
+INSERT INTO post_format_ (name_, max_players_needed_)
+VALUES
+    ('singles', 1),
+    ('doubles', 3),
+    ('group', 10)
+ON CONFLICT (name_) DO NOTHING;
+
 INSERT INTO skill_level_ (name_) 
 VALUES 
     ('beginner'),
@@ -131,3 +370,78 @@ VALUES
     ('advanced'),
     ('expert')
 ON CONFLICT (name_) DO NOTHING;
+
+/*
+ * MIGRATIONS
+ * Bring databases created from an earlier version of this file up to date.
+ * Each statement is a no-op on a fresh database.
+ */
+ALTER TABLE post_
+    ADD COLUMN IF NOT EXISTS format_id_ INT NOT NULL DEFAULT 1 REFERENCES post_format_(id_) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS players_needed_ INT NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS closed_at_ TIMESTAMPTZ;
+
+ALTER TABLE match_set_ ALTER COLUMN match_id_ TYPE BIGINT;
+
+ALTER TABLE user_ ALTER COLUMN password_hash_ TYPE TEXT;
+
+-- Availability now cascades from user_, while shared history outlives a
+-- deleted account. Only columns and keys still in the old layout are
+-- altered, so later runs take no locks and revalidate nothing.
+DO $$
+DECLARE
+    r RECORD;
+BEGIN
+    FOR r IN
+        SELECT k.table_name, k.column_name
+        FROM (VALUES
+            ('challenge_', 'challenger_id_'),
+            ('challenge_', 'opponent_id_'),
+            ('match_', 'reporter_id_'),
+            ('match_', 'opponent_id_'),
+            ('match_', 'winner_id_'),
+            ('tournament_', 'organizer_id_')
+        ) AS k(table_name, column_name)
+        INNER JOIN information_schema.columns c
+            ON c.table_schema = current_schema()
+            AND c.table_name = k.table_name
+            AND c.column_name = k.column_name
+        WHERE c.is_nullable = 'NO'
+    LOOP
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN %I DROP NOT NULL',
+            r.table_name, r.column_name);
+    END LOOP;
+
+    -- confdeltype is 'c' for CASCADE and 'n' for SET NULL
+    FOR r IN
+        SELECT k.table_name, k.column_name, k.delete_rule, fk.conname
+        FROM (VALUES
+            ('timeslot_', 'user_id_', 'c'),
+            ('challenge_', 'challenger_id_', 'n'),
+            ('challenge_', 'opponent_id_', 'n'),
+            ('challenge_', 'winner_id_', 'n'),
+            ('challenge_', 'reported_by_id_', 'n'),
+            ('match_', 'reporter_id_', 'n'),
+            ('match_', 'opponent_id_', 'n'),
+            ('match_', 'winner_id_', 'n'),
+            ('tournament_', 'organizer_id_', 'n'),
+            ('tournament_match_', 'player1_id_', 'n'),
+            ('tournament_match_', 'player2_id_', 'n'),
+            ('tournament_match_', 'winner_id_', 'n')
+        ) AS k(table_name, column_name, delete_rule)
+        INNER JOIN pg_attribute a
+            ON a.attrelid = k.table_name::regclass
+            AND a.attname = k.column_name
+        INNER JOIN pg_constraint fk
+            ON fk.conrelid = a.attrelid
+            AND fk.contype = 'f'
+            AND fk.confrelid = 'user_'::regclass
+            AND fk.conkey = ARRAY[a.attnum]
+        WHERE fk.confdeltype <> k.delete_rule::"char"
+    LOOP
+        EXECUTE format('ALTER TABLE %I DROP CONSTRAINT %I, '
+            'ADD CONSTRAINT %I FOREIGN KEY (%I) REFERENCES user_(id_) ON DELETE %s',
+            r.table_name, r.conname, r.conname, r.column_name,
+            CASE r.delete_rule WHEN 'c' THEN 'CASCADE' ELSE 'SET NULL' END);
+    END LOOP;
+END $$;